@@ -2,31 +2,36 @@ package ksuid
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 const (
 	// lexographic ordering (based on Unicode table) is 0-9A-Za-z
 	base62Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 	zeroString       = "000000000000000000000000000"
-	offsetUppercase  = 10
-	offsetLowercase  = 36
-)
 
-var (
-	errShortBuffer = errors.New("the output buffer is too small to hold to decoded value")
+	// The value returned by base62Value for bytes which are not part of the
+	// base 62 alphabet.
+	invalidBase62 = 0xFF
 )
 
-// Converts a base 62 byte into the number value that it represents.
-func base62Value(digit byte) byte {
-	switch {
-	case digit >= '0' && digit <= '9':
-		return digit - '0'
-	case digit >= 'A' && digit <= 'Z':
-		return offsetUppercase + (digit - 'A')
-	default:
-		return offsetLowercase + (digit - 'a')
+// Lookup table mapping every byte to the number value that it represents in
+// base 62, or invalidBase62 if the byte is not a base 62 character.
+var base62Values = makeBase62Values()
+
+func makeBase62Values() (values [256]byte) {
+	for i := range values {
+		values[i] = invalidBase62
+	}
+	for i := 0; i != len(base62Characters); i++ {
+		values[base62Characters[i]] = byte(i)
 	}
+	return
+}
+
+// Converts a base 62 byte into the number value that it represents, or
+// invalidBase62 if the byte is not a base 62 character.
+func base62Value(digit byte) byte {
+	return base62Values[digit]
 }
 
 // This function encodes the base 62 representation of the src KSUID in binary
@@ -99,6 +104,9 @@ func fastAppendEncodeBase62(dst []byte, src []byte) []byte {
 // is 27 bytes long and dst is 20 bytes long.
 //
 // Any unused bytes in dst will be set to zero.
+//
+// The function returns a *ParseError with an empty Input if src contains
+// invalid characters or represents a value which does not fit in dst.
 func fastDecodeBase62(dst []byte, src []byte) error {
 	const srcBase = 62
 	const dstBase = 4294967296
@@ -139,6 +147,22 @@ func fastDecodeBase62(dst []byte, src []byte) error {
 		base62Value(src[26]),
 	}
 
+	// Valid values are lower than 62 and never have the 7th bit set, so a
+	// single test on the union of all values is enough to detect invalid
+	// characters on the fast path.
+	union := byte(0)
+	for _, c := range parts {
+		union |= c
+	}
+
+	if (union & 0x40) != 0 {
+		for i, c := range parts {
+			if c == invalidBase62 {
+				return &ParseError{Offset: i, Char: src[i], Err: ErrInvalidCharacter}
+			}
+		}
+	}
+
 	n := len(dst)
 	bp := parts[:]
 	bq := [stringEncodedLength]byte{}
@@ -158,7 +182,7 @@ func fastDecodeBase62(dst []byte, src []byte) error {
 		}
 
 		if n < 4 {
-			return errShortBuffer
+			return &ParseError{Err: ErrOverflow}
 		}
 
 		dst[n-4] = byte(remainder >> 24)
@@ -2,6 +2,7 @@ package ksuid
 
 import (
 	"bytes"
+	"errors"
 	"sort"
 	"strings"
 	"testing"
@@ -76,6 +77,62 @@ func TestBase62Value(t *testing.T) {
 	}
 }
 
+func TestBase62ValueInvalid(t *testing.T) {
+	for i := 0; i != 256; i++ {
+		c := byte(i)
+
+		if strings.IndexByte(base62Characters, c) >= 0 {
+			continue
+		}
+
+		if v := base62Value(c); v != invalidBase62 {
+			t.Errorf("bad value for invalid character %q: %d", c, v)
+		}
+	}
+}
+
+func TestParseInvalidCharacters(t *testing.T) {
+	const valid = "0ujtsYcgvSTl8PAuAdqWYSMnLOv"
+
+	for _, c := range []byte{'-', '_', '!', ' ', '/', ':', '@', '[', '`', '{', 0, 0x80, 0xFF} {
+		for _, i := range []int{0, 13, len(valid) - 1} {
+			b := []byte(valid)
+			b[i] = c
+
+			_, err := Parse(string(b))
+
+			var e *ParseError
+			if !errors.As(err, &e) || e.Err != ErrInvalidCharacter {
+				t.Errorf("%q: bad error: %v", b, err)
+			} else if e.Offset != i || e.Char != c {
+				t.Errorf("%q: bad offset or character: %d %q", b, e.Offset, e.Char)
+			}
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(minStringEncoded)
+	f.Add(maxStringEncoded)
+	f.Add("0ujtsYcgvSTl8PAuAdqWYSMnLOv")
+	f.Add("0ujtsYcgvSTl8-AuAdqWYSMnLOv")
+	f.Add("aWgEPTl1tmebfsQzFP4bxwgy80W")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		id, err := Parse(s)
+		if err != nil {
+			if id != Nil {
+				t.Errorf("%q: non-nil KSUID returned with an error: %s", s, id)
+			}
+			return
+		}
+
+		if id.String() != s {
+			t.Errorf("%q: the parsed KSUID does not round-trip: %q", s, id.String())
+		}
+	})
+}
+
 func TestFastAppendEncodeBase62(t *testing.T) {
 	for i := 0; i != 1000; i++ {
 		id := New()
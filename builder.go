@@ -0,0 +1,96 @@
+package ksuid
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrOutOfOrder is returned by CompressedSetBuilder.Add when the KSUID is
+// lower than the one added before it.
+var ErrOutOfOrder = errors.New("the KSUID is lower than the last KSUID added to the set")
+
+var errBuilderFinished = errors.New("the compressed set builder was already finished")
+
+// The amount of encoded data that a builder buffers before writing it.
+const builderBufferSize = 32 * 1024
+
+// CompressedSetBuilder builds a compressed set from KSUIDs added one at a time
+// in ascending order.
+//
+// The set is either written to an io.Writer as it is built, using a bounded
+// amount of memory, or kept in memory and returned by Finish. Either way, the
+// bytes are the same as what AppendCompressed produces for the same KSUIDs.
+//
+// CompressedSetBuilder values are not safe to use concurrently from multiple
+// goroutines.
+type CompressedSetBuilder struct {
+	w   io.Writer
+	e   setEncoder
+	err error
+}
+
+// NewCompressedSetBuilder returns a builder writing the set to w. If w is nil,
+// the set is built in memory and returned by Finish.
+func NewCompressedSetBuilder(w io.Writer) *CompressedSetBuilder {
+	return &CompressedSetBuilder{w: w}
+}
+
+// Add adds id to the set. It returns ErrOutOfOrder if id is lower than the
+// previous KSUID, in which case id is not added, or the error returned by the
+// writer of the builder. Adding the same KSUID multiple times in a row stores
+// it only once.
+func (b *CompressedSetBuilder) Add(id KSUID) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if b.e.started && less(&id, &b.e.lastKSUID) {
+		return ErrOutOfOrder
+	}
+
+	b.e.add(id)
+
+	if b.w != nil && len(b.e.buf) >= builderBufferSize {
+		b.write()
+	}
+
+	return b.err
+}
+
+// Len returns the number of distinct KSUIDs added to the set.
+func (b *CompressedSetBuilder) Len() int {
+	return int(b.e.count)
+}
+
+// Finish completes the set and returns it if the builder has no writer,
+// otherwise it writes the remaining data and returns a nil set.
+//
+// No KSUIDs can be added after calling Finish.
+func (b *CompressedSetBuilder) Finish() (CompressedSet, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	b.e.flush()
+
+	if b.w == nil {
+		set := CompressedSet(b.e.buf)
+		b.e.buf = nil
+		b.err = errBuilderFinished
+		return set, nil
+	}
+
+	if b.write(); b.err != nil {
+		return nil, b.err
+	}
+
+	b.err = errBuilderFinished
+	return nil, nil
+}
+
+func (b *CompressedSetBuilder) write() {
+	if _, err := b.w.Write(b.e.buf); err != nil {
+		b.err = err
+	}
+	b.e.buf = b.e.buf[:0]
+}
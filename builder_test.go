@@ -0,0 +1,119 @@
+package ksuid
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCompressedSetBuilder(t *testing.T) {
+	ids := mixedKSUIDs(5000)
+	expect := Compress(ids...)
+
+	t.Run("buffer", func(t *testing.T) {
+		b := NewCompressedSetBuilder(nil)
+
+		for _, id := range ids {
+			if err := b.Add(id); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Add(id); err != nil {
+				t.Fatal("bad error when adding a duplicate:", err)
+			}
+		}
+
+		if n := b.Len(); n != len(ids) {
+			t.Error("bad length:", n)
+		}
+
+		set, err := b.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(set, expect) {
+			t.Error("bad set built in memory")
+		}
+
+		if err := b.Add(New()); err == nil {
+			t.Error("bad builder accepting KSUIDs after being finished")
+		}
+	})
+
+	t.Run("writer", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		b := NewCompressedSetBuilder(w)
+
+		for _, id := range ids {
+			if err := b.Add(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		set, err := b.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set != nil {
+			t.Error("bad set returned by a builder with a writer")
+		}
+		if !bytes.Equal(w.Bytes(), expect) {
+			t.Error("bad set written to the writer")
+		}
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		b := NewCompressedSetBuilder(nil)
+		b.Add(ids[1])
+
+		if err := b.Add(ids[0]); !errors.Is(err, ErrOutOfOrder) {
+			t.Error("bad error when adding a lower KSUID:", err)
+		}
+
+		b.Add(ids[2])
+
+		set, _ := b.Finish()
+		if !bytes.Equal(set, Compress(ids[1], ids[2])) {
+			t.Error("bad set after rejecting a KSUID:", set)
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		b := NewCompressedSetBuilder(failWriter{})
+
+		var err error
+		for _, id := range ids {
+			if err = b.Add(id); err != nil {
+				break
+			}
+		}
+		if _, finishErr := b.Finish(); err == nil {
+			err = finishErr
+		}
+
+		if !errors.Is(err, errFailWriter) {
+			t.Error("bad error when the writer fails:", err)
+		}
+	})
+}
+
+var errFailWriter = errors.New("fail")
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errFailWriter }
+
+func BenchmarkCompressedSetBuilder(b *testing.B) {
+	ids := make([]KSUID, 1000)
+	for i := range ids {
+		ids[i] = New()
+	}
+	Sort(ids)
+
+	for i := 0; i != b.N; i++ {
+		builder := NewCompressedSetBuilder(nil)
+		for _, id := range ids {
+			builder.Add(id)
+		}
+		builder.Finish()
+	}
+}
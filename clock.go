@@ -0,0 +1,104 @@
+package ksuid
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClockRegression is returned by a Generator configured with
+// FailOnRegression when the clock moves backwards.
+var ErrClockRegression = errors.New("the clock moved backwards since the last KSUID was generated")
+
+// Clock is the interface implemented by the sources of time that a Generator
+// reads the timestamps of KSUIDs from.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock reading the current time from the system, it is
+// used by generators when no clock is configured.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// sleeper is implemented by clocks which control how waiting for time to pass
+// is done, like the FakeClock.
+type sleeper interface {
+	Sleep(time.Duration)
+}
+
+func sleep(clock Clock, d time.Duration) {
+	if s, ok := clock.(sleeper); ok {
+		s.Sleep(d)
+	} else {
+		time.Sleep(d)
+	}
+}
+
+// RegressionPolicy represents the behavior of a Generator when it observes
+// the clock moving backwards, for example after an NTP step.
+type RegressionPolicy int
+
+const (
+	// HoldOnRegression keeps using the timestamp of the last KSUID and
+	// increments its payload until the clock catches up. This is the default
+	// policy.
+	HoldOnRegression RegressionPolicy = iota
+
+	// WaitOnRegression blocks the call to the generator until the clock has
+	// caught up with the last time it observed. Other goroutines are not
+	// blocked while it waits, but they observe the same regression and wait
+	// as well.
+	WaitOnRegression
+
+	// FailOnRegression makes the generator return ErrClockRegression until the
+	// clock has caught up with the last time it observed.
+	FailOnRegression
+)
+
+// FakeClock is a Clock which only moves when told to, it is intended to be
+// used to test programs generating KSUIDs.
+//
+// Calling Sleep on a FakeClock advances its time instead of blocking, which
+// means that generators configured with WaitOnRegression return immediately.
+//
+// FakeClock values are safe to use concurrently from multiple goroutines.
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewFakeClock returns a FakeClock set to t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now satisfies the Clock interface, it returns the current time of c.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Set changes the current time of c to t, which may be before its current
+// time.
+func (c *FakeClock) Set(t time.Time) {
+	c.mutex.Lock()
+	c.now = t
+	c.mutex.Unlock()
+}
+
+// Add moves the current time of c by d, which may be negative.
+func (c *FakeClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	c.mutex.Unlock()
+}
+
+// Sleep advances the current time of c by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Add(d)
+}
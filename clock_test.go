@@ -0,0 +1,43 @@
+package ksuid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+
+	if t0 := clock.Now(); !t0.Equal(now) {
+		t.Error("bad time:", t0, "!=", now)
+	}
+
+	clock.Add(time.Minute)
+
+	if t1 := clock.Now(); !t1.Equal(now.Add(time.Minute)) {
+		t.Error("bad time after Add:", t1)
+	}
+
+	clock.Sleep(time.Minute)
+
+	if t2 := clock.Now(); !t2.Equal(now.Add(2 * time.Minute)) {
+		t.Error("bad time after Sleep:", t2)
+	}
+
+	clock.Set(now)
+
+	if t3 := clock.Now(); !t3.Equal(now) {
+		t.Error("bad time after Set:", t3)
+	}
+}
+
+func TestSystemClock(t *testing.T) {
+	t0 := time.Now()
+	t1 := SystemClock.Now()
+	t2 := time.Now()
+
+	if t1.Before(t0) || t1.After(t2) {
+		t.Error("bad time:", t1, "is not between", t0, "and", t2)
+	}
+}
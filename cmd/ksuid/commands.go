@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/signoz/ksuid"
+)
+
+func gen(args []string) {
+	fs := newFlagSet("gen", "[flags]", "Generate KSUIDs.")
+	addFormatFlags(fs)
+	n := fs.Int("n", 1, "Number of KSUIDs to generate.")
+	at := fs.String("at", "", "Time of the generated KSUIDs, in RFC 3339 format. Defaults to the current time.")
+	fs.Parse(args)
+
+	if *n < 0 || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ids, err := generate(*n, *at)
+	if err != nil {
+		fatalf("Error when generating KSUIDs: %s", err)
+	}
+	printAll(ids)
+}
+
+// generate returns n KSUIDs at the time given by at, or at the current time
+// if it is empty. The KSUIDs generated at the current time read the clock
+// each, so their timestamps increase with the time it takes to generate them.
+func generate(n int, at string) ([]ksuid.KSUID, error) {
+	if at != "" {
+		return ksuid.NewBatch(n, parseTime(at))
+	}
+
+	var g ksuid.Generator
+	ids := make([]ksuid.KSUID, n)
+
+	for i := range ids {
+		id, err := g.Next()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+func parse(args []string) {
+	fs := newFlagSet("parse", "[flags] ids...", "Parse KSUIDs and print them in the format selected by -f.")
+	addFormatFlags(fs)
+	fs.Parse(args)
+	printAll(parseIDs(fs.Args()))
+}
+
+func inspect(args []string) {
+	fs := newFlagSet("inspect", "ids...", "Print the representations and components of KSUIDs.")
+	fs.Parse(args)
+
+	for _, id := range parseIDs(fs.Args()) {
+		printInspect(id)
+	}
+}
+
+func convert(args []string) {
+	fs := newFlagSet("convert", "[flags] ids...", "Convert KSUIDs to another encoding.")
+	to := fs.String("to", "string", "One of string, base58, base32, hex, or uuid.")
+	fs.Parse(args)
+
+	var conv func(ksuid.KSUID) string
+	switch *to {
+	case "string", "base62":
+		conv = ksuid.KSUID.String
+	case "base58":
+		conv = encoder(ksuid.Base58)
+	case "base32":
+		conv = encoder(ksuid.Base32Crockford)
+	case "hex":
+		conv = encoder(ksuid.Hex)
+	case "uuid":
+		conv = formatUUID
+	default:
+		fatalf("Bad encoding: %s", *to)
+	}
+
+	for _, id := range parseIDs(fs.Args()) {
+		fmt.Println(conv(id))
+	}
+}
+
+func between(args []string) {
+	fs := newFlagSet("between", "a b", "Print the order of two KSUIDs and the time elapsed from a to b.")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ids := parseIDs(fs.Args())
+	a, b := ids[0], ids[1]
+
+	order := "="
+	switch ksuid.Compare(a, b) {
+	case -1:
+		order = "<"
+	case 1:
+		order = ">"
+	}
+
+	fmt.Printf("%s %s %s\n", a, order, b)
+	fmt.Println(b.Time().Sub(a.Time()))
+}
+
+func rangeFor(args []string) {
+	fs := newFlagSet("range", "--from T --to T", "Print the inclusive bounds of the KSUIDs generated between two times.")
+	from := fs.String("from", "", "Start of the time range, in RFC 3339 format.")
+	to := fs.String("to", "", "End of the time range, in RFC 3339 format. Defaults to the current time.")
+	fs.Parse(args)
+
+	if *from == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	end := time.Now()
+	if *to != "" {
+		end = parseTime(*to)
+	}
+
+	lo, hi := ksuid.RangeFor(parseTime(*from), end)
+	fmt.Println(lo)
+	fmt.Println(hi)
+}
+
+func newFlagSet(name, params, desc string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ksuid %s %s\n\n%s\n", name, params, desc)
+		if hasFlags(fs) {
+			fmt.Fprintf(fs.Output(), "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+func hasFlags(fs *flag.FlagSet) (ok bool) {
+	fs.VisitAll(func(*flag.Flag) { ok = true })
+	return
+}
+
+// parseIDs parses the KSUIDs in args, which may be in any of the encodings
+// that convert produces. The encoding is detected from the length of the
+// strings, since they all have different lengths.
+func parseIDs(args []string) []ksuid.KSUID {
+	ids := make([]ksuid.KSUID, 0, len(args))
+
+	for _, arg := range args {
+		var id ksuid.KSUID
+		var err error
+
+		switch len(arg) {
+		case ksuid.Base58.EncodedLen():
+			id, err = ksuid.ParseWith(ksuid.Base58, arg)
+		case ksuid.Base32Crockford.EncodedLen():
+			id, err = ksuid.ParseWith(ksuid.Base32Crockford, arg)
+		case ksuid.Hex.EncodedLen():
+			id, err = ksuid.ParseWith(ksuid.Hex, arg)
+		case 36:
+			id, err = parseUUID(arg)
+		default:
+			id, err = ksuid.Parse(arg)
+		}
+
+		if err != nil {
+			fatalf("Error when parsing %q: %s", arg, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		fatalf("Error when parsing time %q: %s", s, err)
+	}
+	return t
+}
+
+func encoder(enc ksuid.Encoding) func(ksuid.KSUID) string {
+	return func(id ksuid.KSUID) string { return id.Format(enc) }
+}
+
+func formatUUID(id ksuid.KSUID) string {
+	u := id.ToUUIDv7()
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func parseUUID(s string) (ksuid.KSUID, error) {
+	var u [16]byte
+
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != len(u) || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return ksuid.Nil, fmt.Errorf("invalid UUID")
+	}
+
+	copy(u[:], b)
+	return ksuid.FromUUIDv7(u)
+}
+
+func fatalf(msg string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, msg+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/signoz/ksuid"
+)
+
+func TestGenerate(t *testing.T) {
+	ids, err := generate(1000, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 1000 {
+		t.Fatal("bad number of KSUIDs:", len(ids))
+	}
+
+	for i := 1; i < len(ids); i++ {
+		if ksuid.Compare(ids[i-1], ids[i]) >= 0 {
+			t.Fatalf("bad order of KSUIDs: %s >= %s", ids[i-1], ids[i])
+		}
+	}
+
+	if first, last := ids[0].Time(), ids[len(ids)-1].Time(); !first.Before(last) {
+		t.Error("bad timestamps of KSUIDs generated at the current time:", first, last)
+	}
+}
+
+func TestGenerateAt(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	ids, err := generate(10, at.Format(time.RFC3339Nano))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		if !id.Time().Equal(at) {
+			t.Error("bad time of KSUID generated at", at, ":", id.Time())
+		}
+	}
+}
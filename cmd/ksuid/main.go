@@ -21,17 +21,86 @@ var (
 	verbose bool
 )
 
+// commands maps the names of subcommands to the functions running them with
+// the remaining arguments of the program.
+var commands = map[string]func(args []string){
+	"gen":     gen,
+	"parse":   parse,
+	"inspect": inspect,
+	"convert": convert,
+	"between": between,
+	"range":   rangeFor,
+}
+
 func init() {
+	addFormatFlags(flag.CommandLine)
 	flag.IntVar(&count, "n", 1, "Number of KSUIDs to generate when called with no other arguments.")
-	flag.StringVar(&format, "f", "string", "One of string, inspect, time, timestamp, payload, raw, or template.")
-	flag.StringVar(&tpltxt, "t", "", "The Go template used to format the output.")
-	flag.BoolVar(&verbose, "v", false, "Turn on verbose mode.")
+	flag.Usage = usage
+}
+
+// addFormatFlags registers the flags controlling how KSUIDs are printed on fs.
+func addFormatFlags(fs *flag.FlagSet) {
+	fs.StringVar(&format, "f", "string", "One of string, inspect, time, timestamp, payload, raw, or template.")
+	fs.StringVar(&tpltxt, "t", "", "The Go template used to format the output.")
+	fs.BoolVar(&verbose, "v", false, "Turn on verbose mode.")
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, `Usage: ksuid [flags] [ids...]
+       ksuid <command> [flags] [args...]
+
+Without a command, ksuid parses the KSUIDs given as arguments, or generates
+new ones if there are none, and prints them in the format selected by -f.
+
+Commands:
+  gen       generate KSUIDs, optionally at a given time
+  parse     parse KSUIDs and print them in the format selected by -f
+  inspect   print the representations and components of KSUIDs
+  convert   convert KSUIDs to another encoding
+  between   print the order and the time delta of two KSUIDs
+  range     print the bounds of the KSUIDs generated in a time range
+
+Commands accept KSUIDs in base62, base58, base32, hex, or UUIDv7 form.
+
+Flags:
+`)
+	flag.PrintDefaults()
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
 	args := flag.Args()
 
+	if len(args) == 0 {
+		for i := 0; i < count; i++ {
+			args = append(args, ksuid.New().String())
+		}
+	}
+
+	var ids []ksuid.KSUID
+	for _, arg := range args {
+		id, err := ksuid.Parse(arg)
+		if err != nil {
+			fmt.Printf("Error when parsing %q: %s\n\n", arg, err)
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+		ids = append(ids, id)
+	}
+
+	printAll(ids)
+}
+
+// printAll prints ids in the format selected by the -f flag.
+func printAll(ids []ksuid.KSUID) {
 	var print func(ksuid.KSUID)
 	switch format {
 	case "string":
@@ -53,23 +122,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	if len(args) == 0 {
-		for i := 0; i < count; i++ {
-			args = append(args, ksuid.New().String())
-		}
-	}
-
-	var ids []ksuid.KSUID
-	for _, arg := range args {
-		id, err := ksuid.Parse(arg)
-		if err != nil {
-			fmt.Printf("Error when parsing %q: %s\n\n", arg, err)
-			flag.PrintDefaults()
-			os.Exit(1)
-		}
-		ids = append(ids, id)
-	}
-
 	for _, id := range ids {
 		if verbose {
 			fmt.Printf("%s: ", id)
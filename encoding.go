@@ -0,0 +1,192 @@
+package ksuid
+
+import (
+	"encoding/binary"
+)
+
+// Encoding is the interface implemented by the text encodings of KSUIDs.
+//
+// All encodings provided by this package produce fixed-length strings from an
+// alphabet sorted in byte order, which means that the encoded strings sort in
+// the same order as the KSUIDs they represent.
+type Encoding interface {
+	// EncodedLen returns the length of the string representation of a KSUID.
+	EncodedLen() int
+
+	// Append appends the string representation of id to dst, returning a
+	// slice to a potentially larger memory area.
+	Append(dst []byte, id KSUID) []byte
+
+	// Parse decodes the string representation of a KSUID. Errors returned by
+	// Parse are of type *ParseError.
+	Parse(s string) (KSUID, error)
+}
+
+var (
+	// Base62 is the default encoding of KSUIDs, used by String and Parse.
+	// Strings are 27 characters long.
+	Base62 Encoding = base62Encoding{}
+
+	// Base32Crockford is the base32 encoding using Douglas Crockford's
+	// alphabet, which is case-insensitive and avoids ambiguous characters.
+	// Strings are 32 uppercase characters long, decoding accepts lowercase
+	// characters and the aliases I and L for 1 and O for 0.
+	Base32Crockford Encoding = newRadixEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ", 32, true, map[byte]byte{
+		'I': '1', 'L': '1', 'O': '0',
+	})
+
+	// Base58 is the base58 encoding using the bitcoin alphabet, which avoids
+	// ambiguous characters and is URL friendly. Strings are 28 characters long.
+	Base58 Encoding = newRadixEncoding("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz", 28, false, nil)
+
+	// Hex is the hexadecimal encoding of the binary representation of KSUIDs.
+	// Strings are 40 lowercase characters long, decoding accepts uppercase
+	// characters.
+	Hex Encoding = newRadixEncoding("0123456789abcdef", 40, true, nil)
+)
+
+// Format returns the string representation of the KSUID in the given
+// encoding, which can be passed through ParseWith.
+func (i KSUID) Format(enc Encoding) string {
+	return string(enc.Append(make([]byte, 0, enc.EncodedLen()), i))
+}
+
+// ParseWith decodes a string representation of a KSUID in the given encoding.
+func ParseWith(enc Encoding, s string) (KSUID, error) {
+	return enc.Parse(s)
+}
+
+type base62Encoding struct{}
+
+func (base62Encoding) EncodedLen() int { return stringEncodedLength }
+
+func (base62Encoding) Append(dst []byte, id KSUID) []byte { return id.Append(dst) }
+
+func (base62Encoding) Parse(s string) (KSUID, error) { return Parse(s) }
+
+// radixEncoding is the implementation of fixed-length encodings in an
+// arbitrary base, the KSUID is converted to the base of the encoding with the
+// same algorithm as fastEncodeBase62.
+type radixEncoding struct {
+	alphabet string
+	length   int
+	values   [256]byte
+}
+
+// newRadixEncoding constructs an encoding for the given alphabet, where each
+// alias decodes to the same value as the character it is mapped to. When
+// foldCase is true, letters are decoded the same regardless of their case.
+func newRadixEncoding(alphabet string, length int, foldCase bool, aliases map[byte]byte) *radixEncoding {
+	enc := &radixEncoding{alphabet: alphabet, length: length}
+
+	for i := range enc.values {
+		enc.values[i] = invalidBase62
+	}
+	for i := 0; i != len(alphabet); i++ {
+		enc.values[alphabet[i]] = byte(i)
+	}
+	for alias, c := range aliases {
+		enc.values[alias] = enc.values[c]
+	}
+
+	if foldCase {
+		for c := 'A'; c <= 'Z'; c++ {
+			upper, lower := byte(c), byte(c-'A'+'a')
+
+			if v := enc.values[upper]; v != invalidBase62 {
+				enc.values[lower] = v
+			} else {
+				enc.values[upper] = enc.values[lower]
+			}
+		}
+	}
+
+	return enc
+}
+
+func (enc *radixEncoding) EncodedLen() int { return enc.length }
+
+func (enc *radixEncoding) Append(dst []byte, id KSUID) []byte {
+	const srcBase = 4294967296
+	dstBase := uint64(len(enc.alphabet))
+
+	dst = reserve(dst, enc.length)
+	n := len(dst)
+	b := dst[n : n+enc.length]
+
+	parts := [5]uint32{
+		binary.BigEndian.Uint32(id[0:4]),
+		binary.BigEndian.Uint32(id[4:8]),
+		binary.BigEndian.Uint32(id[8:12]),
+		binary.BigEndian.Uint32(id[12:16]),
+		binary.BigEndian.Uint32(id[16:20]),
+	}
+
+	i := len(b)
+	bp := parts[:]
+	bq := [5]uint32{}
+
+	for len(bp) != 0 {
+		quotient := bq[:0]
+		remainder := uint64(0)
+
+		for _, c := range bp {
+			value := uint64(c) + remainder*srcBase
+			digit := value / dstBase
+			remainder = value % dstBase
+
+			if len(quotient) != 0 || digit != 0 {
+				quotient = append(quotient, uint32(digit))
+			}
+		}
+
+		i--
+		b[i] = enc.alphabet[remainder]
+		bp = quotient
+	}
+
+	// Pad with the zero digit of the alphabet for all bytes that were not set.
+	for i != 0 {
+		i--
+		b[i] = enc.alphabet[0]
+	}
+
+	return dst[:n+enc.length]
+}
+
+func (enc *radixEncoding) Parse(s string) (KSUID, error) {
+	if len(s) != enc.length {
+		return Nil, &ParseError{Input: s, Offset: len(s), Length: enc.length, Err: ErrInvalidLength}
+	}
+
+	srcBase := uint64(len(enc.alphabet))
+	parts := [5]uint32{} // little endian, parts[0] holds the lowest 32 bits
+
+	for i := 0; i != len(s); i++ {
+		digit := enc.values[s[i]]
+
+		if digit == invalidBase62 {
+			return Nil, &ParseError{Input: s, Offset: i, Char: s[i], Err: ErrInvalidCharacter}
+		}
+
+		carry := uint64(digit)
+
+		for j := range parts {
+			value := uint64(parts[j])*srcBase + carry
+			parts[j] = uint32(value)
+			carry = value >> 32
+		}
+
+		if carry != 0 {
+			return Nil, &ParseError{Input: s, Err: ErrOverflow}
+		}
+	}
+
+	var id KSUID
+	binary.BigEndian.PutUint32(id[0:4], parts[4])
+	binary.BigEndian.PutUint32(id[4:8], parts[3])
+	binary.BigEndian.PutUint32(id[8:12], parts[2])
+	binary.BigEndian.PutUint32(id[12:16], parts[1])
+	binary.BigEndian.PutUint32(id[16:20], parts[0])
+	return id, nil
+}
@@ -0,0 +1,161 @@
+package ksuid
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var encodings = []struct {
+	name string
+	enc  Encoding
+}{
+	{"base62", Base62},
+	{"base32crockford", Base32Crockford},
+	{"base58", Base58},
+	{"hex", Hex},
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	for _, test := range encodings {
+		t.Run(test.name, func(t *testing.T) {
+			ids := []KSUID{Nil, Max, Nil.Next(), Max.Prev()}
+			for i := 0; i != 1000; i++ {
+				ids = append(ids, New())
+			}
+
+			for _, id := range ids {
+				s := id.Format(test.enc)
+
+				if len(s) != test.enc.EncodedLen() {
+					t.Fatalf("bad length of %q: %d != %d", s, len(s), test.enc.EncodedLen())
+				}
+
+				parsed, err := ParseWith(test.enc, s)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if parsed != id {
+					t.Fatalf("%q does not round-trip: %s != %s", s, parsed, id)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodingOrder(t *testing.T) {
+	ids := []KSUID{Nil, Max}
+	for i := 0; i != 1000; i++ {
+		ids = append(ids, New())
+	}
+	Sort(ids)
+
+	for _, test := range encodings {
+		t.Run(test.name, func(t *testing.T) {
+			strs := make([]string, len(ids))
+
+			for i, id := range ids {
+				strs[i] = id.Format(test.enc)
+			}
+
+			if !sort.StringsAreSorted(strs) {
+				t.Error("encoded KSUIDs do not sort in the order of the KSUIDs")
+			}
+		})
+	}
+}
+
+func TestEncodingValues(t *testing.T) {
+	id := New()
+
+	if s := id.Format(Base62); s != id.String() {
+		t.Error("bad base62 string:", s, "!=", id.String())
+	}
+
+	if s := id.Format(Hex); s != hex.EncodeToString(id[:]) {
+		t.Error("bad hex string:", s)
+	}
+
+	if s := Max.Format(Base32Crockford); s != strings.Repeat("Z", 32) {
+		t.Error("bad base32 string for the max KSUID:", s)
+	}
+
+	if s := Nil.Format(Base58); s != strings.Repeat("1", 28) {
+		t.Error("bad base58 string for the nil KSUID:", s)
+	}
+}
+
+func TestEncodingAliases(t *testing.T) {
+	id := New()
+
+	tests := []struct {
+		enc Encoding
+		s   string
+	}{
+		{Hex, strings.ToUpper(id.Format(Hex))},
+		{Base32Crockford, strings.ToLower(id.Format(Base32Crockford))},
+		{Base32Crockford, strings.NewReplacer("0", "O", "1", "l").Replace(id.Format(Base32Crockford))},
+	}
+
+	for _, test := range tests {
+		parsed, err := ParseWith(test.enc, test.s)
+		if err != nil {
+			t.Error(err)
+		} else if parsed != id {
+			t.Errorf("%q: %s != %s", test.s, parsed, id)
+		}
+	}
+}
+
+func TestEncodingErrors(t *testing.T) {
+	tests := []struct {
+		enc Encoding
+		s   string
+		err error
+	}{
+		{Hex, "00", ErrInvalidLength},
+		{Hex, strings.Repeat("0", 39) + "g", ErrInvalidCharacter},
+		{Base32Crockford, strings.Repeat("0", 31) + "U", ErrInvalidCharacter},
+		{Base58, strings.Repeat("1", 27) + "0", ErrInvalidCharacter},
+		{Base58, strings.Repeat("z", 28), ErrOverflow},
+		{Base62, "0", ErrInvalidLength},
+	}
+
+	for _, test := range tests {
+		_, err := ParseWith(test.enc, test.s)
+
+		var e *ParseError
+		if !errors.As(err, &e) || !errors.Is(err, test.err) {
+			t.Errorf("%q: bad error: %v", test.s, err)
+		}
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	id := New()
+
+	for _, test := range encodings {
+		b.Run(test.name, func(b *testing.B) {
+			a := make([]byte, 0, test.enc.EncodedLen())
+			for i := 0; i != b.N; i++ {
+				test.enc.Append(a, id)
+			}
+		})
+	}
+}
+
+func BenchmarkParseWith(b *testing.B) {
+	id := New()
+
+	for _, test := range encodings {
+		b.Run(test.name, func(b *testing.B) {
+			s := id.Format(test.enc)
+			for i := 0; i != b.N; i++ {
+				ParseWith(test.enc, s)
+			}
+		})
+	}
+}
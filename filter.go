@@ -0,0 +1,178 @@
+package ksuid
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+var (
+	// ErrFilterMismatch is returned by Filter.Union when the filters were not
+	// created with the same parameters.
+	ErrFilterMismatch = errors.New("Valid filter unions combine filters with the same size and number of hashes")
+
+	// ErrInvalidFilter is returned by Filter.UnmarshalBinary when the data was
+	// not produced by Filter.MarshalBinary.
+	ErrInvalidFilter = errors.New("Valid serialized filters start with a version and parameters matching their length")
+)
+
+const (
+	filterVersion    = 1
+	filterHeaderSize = 1 + 1 + 8 // version, number of hashes, number of bits
+	filterMaxHashes  = 32
+)
+
+// Filter is a bloom filter of KSUIDs, which answers whether a KSUID may have
+// been added to it, or was definitely not.
+//
+// The payload of KSUIDs is random, so the filter uses its bits directly as
+// the two hashes combined by double hashing instead of hashing the whole
+// KSUID. The rate of false positives holds for KSUIDs with random payloads
+// only: the KSUIDs of a Sequence share all but the last 2 bytes of their
+// payload, so they set overlapping bits and see a much higher rate.
+//
+// The zero-value is an empty filter which reports that it may contain any
+// KSUID. Filter values are not safe to use concurrently from multiple
+// goroutines.
+type Filter struct {
+	bits   []uint64
+	hashes int
+}
+
+// NewFilter returns a filter sized to hold n KSUIDs with a rate of false
+// positives of p. It panics if p is not between 0 and 1.
+func NewFilter(n int, p float64) *Filter {
+	if !(p > 0 && p < 1) {
+		panic("ksuid: the rate of false positives of a filter must be between 0 and 1")
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	// m = -n.ln(p) / ln(2)^2 and k = m/n.ln(2) are the optimal number of bits
+	// and number of hashes of a bloom filter.
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+
+	if k < 1 {
+		k = 1
+	}
+	if k > filterMaxHashes {
+		k = filterMaxHashes
+	}
+
+	return &Filter{
+		bits:   make([]uint64, (uint64(m)+63)/64),
+		hashes: k,
+	}
+}
+
+// Add adds id to the filter.
+func (f *Filter) Add(id KSUID) {
+	if len(f.bits) == 0 {
+		return
+	}
+
+	h1, h2 := filterHashes(id)
+	m := uint64(64 * len(f.bits))
+
+	for i := 0; i != f.hashes; i++ {
+		b := h1 % m
+		f.bits[b/64] |= 1 << (b % 64)
+		h1 += h2
+	}
+}
+
+// MayContain returns false if id was never added to the filter, or true if it
+// may have been.
+func (f *Filter) MayContain(id KSUID) bool {
+	if len(f.bits) == 0 {
+		return true
+	}
+
+	h1, h2 := filterHashes(id)
+	m := uint64(64 * len(f.bits))
+
+	for i := 0; i != f.hashes; i++ {
+		b := h1 % m
+		if f.bits[b/64]&(1<<(b%64)) == 0 {
+			return false
+		}
+		h1 += h2
+	}
+
+	return true
+}
+
+// Union adds all KSUIDs of other to f. Both filters must have been created
+// with the same parameters, otherwise ErrFilterMismatch is returned.
+func (f *Filter) Union(other *Filter) error {
+	if len(f.bits) != len(other.bits) || f.hashes != other.hashes {
+		return ErrFilterMismatch
+	}
+	for i, b := range other.bits {
+		f.bits[i] |= b
+	}
+	return nil
+}
+
+// FillRatio returns the fraction of bits set in the filter, the rate of false
+// positives of the filter is about FillRatio to the power of the number of
+// hashes.
+func (f *Filter) FillRatio() float64 {
+	if len(f.bits) == 0 {
+		return 1
+	}
+	n := 0
+	for _, b := range f.bits {
+		n += bits.OnesCount64(b)
+	}
+	return float64(n) / float64(64*len(f.bits))
+}
+
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	b := make([]byte, filterHeaderSize+8*len(f.bits))
+	b[0] = filterVersion
+	b[1] = byte(f.hashes)
+	binary.BigEndian.PutUint64(b[2:], uint64(64*len(f.bits)))
+
+	for i, w := range f.bits {
+		binary.BigEndian.PutUint64(b[filterHeaderSize+8*i:], w)
+	}
+	return b, nil
+}
+
+func (f *Filter) UnmarshalBinary(b []byte) error {
+	if len(b) < filterHeaderSize || b[0] != filterVersion {
+		return ErrInvalidFilter
+	}
+
+	hashes := int(b[1])
+	m := binary.BigEndian.Uint64(b[2:])
+	data := b[filterHeaderSize:]
+
+	// The zero-value of Filter is serialized with no bits and no hashes.
+	if (m == 0) != (hashes == 0) || hashes > filterMaxHashes || m%64 != 0 || m/8 != uint64(len(data)) {
+		return ErrInvalidFilter
+	}
+
+	words := make([]uint64, m/64)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(data[8*i:])
+	}
+
+	f.bits = words
+	f.hashes = hashes
+	return nil
+}
+
+// filterHashes returns the two hashes of id used for double hashing, taken
+// directly from the random bytes of its payload: h1 is the 8 last bytes of the
+// payload, and h2 the 4 first bytes, made odd so it is never zero.
+func filterHashes(id KSUID) (h1, h2 uint64) {
+	payload := id[timestampLengthInBytes:]
+	h1 = binary.BigEndian.Uint64(payload[4:])
+	h2 = uint64(binary.BigEndian.Uint32(payload[:4]))<<1 | 1
+	return
+}
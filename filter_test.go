@@ -0,0 +1,191 @@
+package ksuid
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	const n = 10000
+	const p = 0.01
+
+	// A sequence produces at most 65536 KSUIDs, a new one is started when it
+	// runs out.
+	seq, seqlen := Sequence{}, 0
+
+	// The rate of false positives is only bounded for random payloads, the
+	// KSUIDs of a sequence must still never be false negatives.
+	tests := []struct {
+		scenario string
+		next     func(*testing.T) KSUID
+		maxRate  float64
+	}{
+		{
+			scenario: "random",
+			next:     func(*testing.T) KSUID { return New() },
+			maxRate:  2 * p,
+		},
+		{
+			scenario: "sequence",
+			maxRate:  1,
+			next: func(t *testing.T) KSUID {
+				if seqlen%65536 == 0 {
+					seq = Sequence{Seed: New()}
+				}
+				seqlen++
+
+				id, err := seq.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				return id
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			f := NewFilter(n, p)
+			ids := make([]KSUID, n)
+
+			for i := range ids {
+				ids[i] = test.next(t)
+				f.Add(ids[i])
+			}
+
+			for _, id := range ids {
+				if !f.MayContain(id) {
+					t.Fatal("false negative:", id)
+				}
+			}
+
+			positives := 0
+			for i := 0; i != 10*n; i++ {
+				if f.MayContain(test.next(t)) {
+					positives++
+				}
+			}
+
+			if rate := float64(positives) / (10 * n); rate > test.maxRate {
+				t.Errorf("bad rate of false positives: %g (fill ratio %g)", rate, f.FillRatio())
+			} else {
+				t.Logf("rate of false positives: %g (fill ratio %g)", rate, f.FillRatio())
+			}
+		})
+	}
+}
+
+func TestFilterZeroValue(t *testing.T) {
+	f := Filter{}
+	f.Add(New())
+
+	if !f.MayContain(New()) {
+		t.Error("bad zero-value filter excluding a KSUID")
+	}
+
+	b, _ := f.MarshalBinary()
+	if err := f.UnmarshalBinary(b); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFilterUnion(t *testing.T) {
+	f1 := NewFilter(100, 0.01)
+	f2 := NewFilter(100, 0.01)
+	id1, id2 := New(), New()
+
+	f1.Add(id1)
+	f2.Add(id2)
+
+	if err := f1.Union(f2); err != nil {
+		t.Fatal(err)
+	}
+	if !f1.MayContain(id1) || !f1.MayContain(id2) {
+		t.Error("bad union missing a KSUID")
+	}
+
+	if err := f1.Union(NewFilter(1000, 0.01)); !errors.Is(err, ErrFilterMismatch) {
+		t.Error("bad error on the union of filters of different sizes:", err)
+	}
+	if err := f1.Union(NewFilter(100, 0.001)); !errors.Is(err, ErrFilterMismatch) {
+		t.Error("bad error on the union of filters with different parameters:", err)
+	}
+}
+
+func TestFilterMarshalBinary(t *testing.T) {
+	f1 := NewFilter(1000, 0.01)
+	ids := make([]KSUID, 1000)
+	for i := range ids {
+		ids[i] = New()
+		f1.Add(ids[i])
+	}
+
+	b, err := f1.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f2 := &Filter{}
+	if err := f2.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		if !f2.MayContain(id) {
+			t.Fatal("bad filter decoded from its binary form, missing:", id)
+		}
+	}
+
+	if b2, _ := f2.MarshalBinary(); !bytes.Equal(b, b2) {
+		t.Error("bad binary form of the decoded filter")
+	}
+
+	invalid := [][]byte{
+		nil,
+		b[:filterHeaderSize],
+		b[:len(b)-1],
+		append([]byte{2}, b[1:]...),
+		append([]byte{filterVersion, 0}, b[2:]...),
+		append([]byte{filterVersion, filterMaxHashes + 1}, b[2:]...),
+	}
+
+	for _, data := range invalid {
+		if err := f2.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("bad error decoding %.12x: %v", data, err)
+		}
+	}
+}
+
+func TestNewFilterPanics(t *testing.T) {
+	for _, p := range []float64{0, 1, -1, 2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("bad filter created with a rate of false positives of", p)
+				}
+			}()
+			NewFilter(10, p)
+		}()
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	f := NewFilter(1000000, 0.01)
+	ids := make([]KSUID, 1000)
+	for i := range ids {
+		ids[i] = New()
+	}
+
+	b.Run("Add", func(b *testing.B) {
+		for i := 0; i != b.N; i++ {
+			f.Add(ids[i%len(ids)])
+		}
+	})
+
+	b.Run("MayContain", func(b *testing.B) {
+		for i := 0; i != b.N; i++ {
+			f.MayContain(ids[i%len(ids)])
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package ksuid
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+var errGeneratorExhausted = errors.New("the generator has produced the highest possible KSUID")
+
+// Generator is a KSUID generator which guarantees that each KSUID it produces
+// is strictly greater than the previous one.
+//
+// When the clock has advanced since the last call, the generator produces a
+// KSUID with a fresh random payload. Otherwise the payload of the previous
+// KSUID is incremented, carrying into the timestamp if it overflows, so IDs
+// produced within the same nanosecond still sort in generation order.
+//
+// A typical usage of a Generator looks like this:
+//
+//	var gen ksuid.Generator
+//	id, err := gen.Next()
+//
+// The clock that timestamps are read from and the behavior of the generator
+// when it observes the clock moving backwards are configurable:
+//
+//	gen := ksuid.Generator{
+//		Clock:        ksuid.SystemClock,
+//		OnRegression: ksuid.FailOnRegression,
+//	}
+//	id, err := gen.Next()
+//
+// The zero-value is ready to use, it reads the time from SystemClock and
+// holds the last timestamp when the clock moves backwards. Generator values
+// are safe to use concurrently from multiple goroutines, but their fields must
+// not be modified once they are in use.
+type Generator struct {
+	// Clock is the source of time of the generator, SystemClock is used when
+	// it is nil.
+	Clock Clock
+
+	// OnRegression configures how the generator behaves when the clock moves
+	// backwards.
+	OnRegression RegressionPolicy
+
+	mutex sync.Mutex
+	last  KSUID
+	now   uint64 // last timestamp read from the clock
+}
+
+// Next produces the next KSUID of the generator.
+func (g *Generator) Next() (KSUID, error) {
+	var id [1]KSUID
+
+	if err := g.Fill(id[:]); err != nil {
+		return Nil, err
+	}
+
+	return id[0], nil
+}
+
+// Fill produces len(dst) KSUIDs into dst, in strictly increasing order and
+// greater than all KSUIDs produced before by the generator.
+//
+// Fill is equivalent to calling Next for each element of dst, but reads the
+// clock and the random payload only once for the whole batch: the KSUIDs after
+// the first one have the payload of the KSUID before them incremented, like
+// KSUIDs generated within the same nanosecond. On error, the generator is left
+// unchanged and the content of dst is unspecified.
+func (g *Generator) Fill(dst []KSUID) error {
+	if len(dst) == 0 {
+		return nil
+	}
+
+	var id KSUID
+
+	// Read the random payload before acquiring the lock so goroutines contending
+	// on the generator don't also wait on the source of randomness.
+	if err := readRandom(id[timestampLengthInBytes:]); err != nil {
+		return err
+	}
+
+	clock := g.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	ts, err := g.timestamp(clock)
+	if err != nil {
+		return err
+	}
+
+	last := g.last
+
+	for i := range dst {
+		if ts > last.Timestamp() {
+			binary.BigEndian.PutUint64(id[:timestampLengthInBytes], ts)
+		} else {
+			if last == Max {
+				return errGeneratorExhausted
+			}
+			id = last.Next()
+		}
+
+		dst[i] = id
+		last = id
+	}
+
+	g.now = ts
+	g.last = last
+	return nil
+}
+
+// timestamp reads the current timestamp from clock, applying the regression
+// policy of the generator. The generator must be locked.
+func (g *Generator) timestamp(clock Clock) (uint64, error) {
+	ts, err := checkedTimeToCorrectedUTCTimestamp(clock.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if ts < g.now {
+		switch g.OnRegression {
+		case WaitOnRegression:
+			// The lock is released while sleeping so other goroutines are not
+			// held for the whole regression, the last timestamp may have moved
+			// forward when it is acquired again.
+			for ts < g.now {
+				d := time.Duration(g.now - ts)
+
+				g.mutex.Unlock()
+				sleep(clock, d)
+				g.mutex.Lock()
+
+				if ts, err = checkedTimeToCorrectedUTCTimestamp(clock.Now()); err != nil {
+					return 0, err
+				}
+			}
+		case FailOnRegression:
+			return 0, ErrClockRegression
+		default:
+			ts = g.now
+		}
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,256 @@
+package ksuid
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenerator(t *testing.T) {
+	var gen Generator
+
+	ids := make([]KSUID, 10000)
+
+	for i := range ids {
+		id, err := gen.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+
+	for i := 1; i < len(ids); i++ {
+		if Compare(ids[i-1], ids[i]) >= 0 {
+			t.Fatalf("KSUIDs are not strictly increasing at index %d: %s >= %s", i, ids[i-1], ids[i])
+		}
+	}
+}
+
+func TestGeneratorSameTimestamp(t *testing.T) {
+	// Pretend the last KSUID was produced in the future so the generator has
+	// to increment the payload of the previous KSUID.
+	last, _ := FromParts(time.Now().Add(time.Hour), []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 41})
+	gen := Generator{last: last}
+
+	id, err := gen.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != last.Next() {
+		t.Error("bad KSUID:", id, "!=", last.Next())
+	}
+}
+
+func TestGeneratorPayloadOverflow(t *testing.T) {
+	last, _ := FromParts(time.Now().Add(time.Hour), []byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255})
+	gen := Generator{last: last}
+
+	id, err := gen.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id.Timestamp() != last.Timestamp()+1 {
+		t.Error("timestamp was not incremented on payload overflow:", id.Timestamp(), "!=", last.Timestamp()+1)
+	}
+
+	if Compare(last, id) >= 0 {
+		t.Error("KSUIDs are not strictly increasing:", last, ">=", id)
+	}
+}
+
+func TestGeneratorExhausted(t *testing.T) {
+	gen := Generator{last: Max}
+
+	if _, err := gen.Next(); err == nil {
+		t.Error("no error returned after producing the highest possible KSUID")
+	}
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	const goroutines = 8
+	const count = 10000
+
+	var gen Generator
+	var wg sync.WaitGroup
+
+	results := make([][]KSUID, goroutines)
+
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids := make([]KSUID, count)
+			for j := range ids {
+				ids[j], _ = gen.Next()
+			}
+			results[i] = ids
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[KSUID]struct{}, goroutines*count)
+
+	for _, ids := range results {
+		for j, id := range ids {
+			if _, dupe := seen[id]; dupe {
+				t.Fatal("duplicate KSUID found:", id)
+			}
+			seen[id] = struct{}{}
+
+			if j != 0 && Compare(ids[j-1], id) >= 0 {
+				t.Fatalf("KSUIDs produced by a single goroutine are not strictly increasing: %s >= %s", ids[j-1], id)
+			}
+		}
+	}
+}
+
+func BenchmarkGenerator(b *testing.B) {
+	var gen Generator
+
+	for i := 0; i != b.N; i++ {
+		gen.Next()
+	}
+}
+
+func TestGeneratorClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gen := Generator{Clock: NewFakeClock(now)}
+
+	id1, _ := gen.Next()
+	id2, _ := gen.Next()
+
+	if !id1.Time().Equal(now) {
+		t.Error("bad time:", id1.Time(), "!=", now)
+	}
+
+	if id2 != id1.Next() {
+		t.Error("KSUIDs generated at the same time are not consecutive:", id1, id2)
+	}
+}
+
+func TestGeneratorRegression(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("hold", func(t *testing.T) {
+		clock := NewFakeClock(now)
+		gen := Generator{Clock: clock, OnRegression: HoldOnRegression}
+
+		id1, _ := gen.Next()
+		clock.Add(-time.Second)
+		id2, err := gen.Next()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id2 != id1.Next() {
+			t.Error("the generator did not hold the last timestamp:", id1, id2)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		clock := NewFakeClock(now)
+		gen := Generator{Clock: clock, OnRegression: WaitOnRegression}
+
+		gen.Next()
+		clock.Add(-time.Second)
+		id, err := gen.Next()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !clock.Now().Equal(now) {
+			t.Error("the generator did not wait for the clock to catch up:", clock.Now(), "!=", now)
+		}
+		if id.Time().Before(now) {
+			t.Error("the KSUID was generated before the clock caught up:", id.Time())
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		clock := NewFakeClock(now)
+		gen := Generator{Clock: clock, OnRegression: FailOnRegression}
+
+		id1, _ := gen.Next()
+		clock.Add(-time.Second)
+
+		if _, err := gen.Next(); err != ErrClockRegression {
+			t.Error("bad error:", err)
+		}
+
+		clock.Set(now)
+		id2, err := gen.Next()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Compare(id1, id2) >= 0 {
+			t.Error("KSUIDs are not strictly increasing:", id1, ">=", id2)
+		}
+	})
+}
+
+func TestGeneratorFill(t *testing.T) {
+	gen := Generator{Clock: NewFakeClock(time.Now())}
+	first, _ := gen.Next()
+
+	ids := make([]KSUID, 1000)
+	if err := gen.Fill(ids); err != nil {
+		t.Fatal(err)
+	}
+
+	prev := first
+	for _, id := range ids {
+		if Compare(prev, id) >= 0 {
+			t.Fatal("KSUIDs are not strictly increasing:", prev, ">=", id)
+		}
+		prev = id
+	}
+
+	if next, _ := gen.Next(); Compare(prev, next) >= 0 {
+		t.Error("KSUID generated after the batch is not greater:", prev, ">=", next)
+	}
+}
+
+// lockCheckClock is a FakeClock which reports whether the mutex of a
+// generator was held when it was asked to sleep.
+type lockCheckClock struct {
+	*FakeClock
+	gen    *Generator
+	locked bool
+}
+
+func (c *lockCheckClock) Sleep(d time.Duration) {
+	if c.gen.mutex.TryLock() {
+		c.gen.mutex.Unlock()
+	} else {
+		c.locked = true
+	}
+	c.FakeClock.Sleep(d)
+}
+
+func TestGeneratorWaitUnlocked(t *testing.T) {
+	now := time.Now()
+	clock := &lockCheckClock{FakeClock: NewFakeClock(now)}
+	gen := &Generator{Clock: clock, OnRegression: WaitOnRegression}
+	clock.gen = gen
+
+	gen.Next()
+	clock.Add(-time.Second)
+
+	if _, err := gen.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if clock.locked {
+		t.Error("the generator held its lock while waiting for the clock")
+	}
+}
+
+func TestGeneratorTimeOutOfRange(t *testing.T) {
+	gen := Generator{Clock: NewFakeClock(time.Unix(0, 0))}
+
+	if _, err := gen.Next(); err != ErrTimeOutOfRange {
+		t.Error("bad error:", err)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/signoz/ksuid
 
-go 1.12
+go 1.18
@@ -5,9 +5,11 @@ import (
 	"crypto/rand"
 	"database/sql/driver"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/bits"
 	"sync"
 	"time"
 )
@@ -18,6 +20,10 @@ const (
 	// This number (14e8 in seconds) was picked to be easy to remember.
 	epochStamp int64 = 1400000000000000000
 
+	// The timestamp of MaxTime, higher timestamps have times which overflow
+	// the nanosecond UNIX timestamps of Go.
+	maxTimestamp = uint64(math.MaxInt64 - epochStamp)
+
 	// Timestamp is a uint32
 	timestampLengthInBytes = 8
 
@@ -48,10 +54,39 @@ var (
 	randMutex  = sync.Mutex{}
 	randBuffer = [payloadLengthInBytes]byte{}
 
-	errSize        = fmt.Errorf("Valid KSUIDs are %v bytes", byteLength)
-	errStrSize     = fmt.Errorf("Valid encoded KSUIDs are %v characters", stringEncodedLength)
-	errStrValue    = fmt.Errorf("Valid encoded KSUIDs are bounded by %s and %s", minStringEncoded, maxStringEncoded)
-	errPayloadSize = fmt.Errorf("Valid KSUID payloads are %v bytes", payloadLengthInBytes)
+	// ErrInvalidLength is the error wrapped by a ParseError when the input
+	// does not have the length of a binary or string encoded KSUID, the
+	// Length field of the ParseError holds the expected length.
+	ErrInvalidLength = errors.New("Valid KSUIDs have the length of their binary representation or of their text encoding")
+
+	// ErrInvalidCharacter is the error wrapped by a ParseError when a string
+	// encoded KSUID contains a character outside of the alphabet of its
+	// encoding.
+	ErrInvalidCharacter = errors.New("Valid encoded KSUIDs only contain characters from the alphabet of their encoding")
+
+	// ErrOverflow is the error wrapped by a ParseError when a string encoded
+	// KSUID represents a value which does not fit in 20 bytes, like base62
+	// strings greater than the encoding of Max.
+	ErrOverflow = fmt.Errorf("Valid encoded KSUIDs represent values of at most %v bytes", byteLength)
+
+	// ErrInvalidPayloadLength is the error wrapped by a ParseError when a
+	// payload does not have the length of a KSUID payload.
+	ErrInvalidPayloadLength = fmt.Errorf("Valid KSUID payloads are %v bytes", payloadLengthInBytes)
+
+	// ErrTimeOutOfRange is returned when attempting to construct a KSUID from
+	// a time which is not within MinTime and MaxTime.
+	ErrTimeOutOfRange = fmt.Errorf("Valid KSUID times are between %s and %s", MinTime.Format(time.RFC3339Nano), MaxTime.Format(time.RFC3339Nano))
+
+	// ErrNegativeCount is returned by NewBatch when asked for a negative
+	// number of KSUIDs.
+	ErrNegativeCount = errors.New("Valid batches hold zero or more KSUIDs")
+
+	// MinTime is the earliest time that can be represented by a KSUID, it is
+	// the KSUID epoch.
+	MinTime = time.Unix(0, epochStamp).UTC()
+	// MaxTime is the latest time that can be represented by a KSUID, it is
+	// limited by the range of nanosecond UNIX timestamps in Go.
+	MaxTime = time.Unix(0, math.MaxInt64).UTC()
 
 	// Represents a completely empty (invalid) KSUID
 	Nil KSUID
@@ -59,6 +94,53 @@ var (
 	Max = KSUID{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255}
 )
 
+// ParseError is the error type returned when a KSUID cannot be decoded from
+// its binary or string representation, or constructed from its parts.
+//
+// The Err field holds one of ErrInvalidLength, ErrInvalidCharacter,
+// ErrOverflow or ErrInvalidPayloadLength, and can be tested with errors.Is.
+type ParseError struct {
+	// Input is the value which could not be decoded.
+	Input string
+
+	// Offset is the position in Input at which the error was detected. When
+	// Err is ErrInvalidLength or ErrInvalidPayloadLength it is the length of
+	// Input.
+	Offset int
+
+	// Length is the length that Input was expected to have when Err is
+	// ErrInvalidLength or ErrInvalidPayloadLength, or zero if several lengths
+	// are accepted.
+	Length int
+
+	// Char is the offending byte when Err is ErrInvalidCharacter.
+	Char byte
+
+	// Err is the reason why the input could not be decoded.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *ParseError) Error() string {
+	if e.Err == ErrInvalidCharacter {
+		return fmt.Sprintf("ksuid: invalid character %q at offset %d of %q: %v", e.Char, e.Offset, e.Input, e.Err)
+	}
+	if e.Length != 0 {
+		return fmt.Sprintf("ksuid: cannot parse %q of length %d, expected %d: %v", e.Input, e.Offset, e.Length, e.Err)
+	}
+	return fmt.Sprintf("ksuid: cannot parse %q: %v", e.Input, e.Err)
+}
+
+// Unwrap returns the reason why the input could not be decoded, making it
+// possible to use errors.Is on ParseError values.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func lengthError(b []byte, length int, err error) error {
+	return &ParseError{Input: string(b), Offset: len(b), Length: length, Err: err}
+}
+
 // Append appends the string representation of i to b, returning a slice to a
 // potentially larger memory area.
 func (i KSUID) Append(b []byte) []byte {
@@ -170,14 +252,14 @@ func (i *KSUID) scan(b []byte) error {
 	case stringEncodedLength:
 		return i.UnmarshalText(b)
 	default:
-		return errSize
+		return lengthError(b, 0, ErrInvalidLength)
 	}
 }
 
 // Parse decodes a string-encoded representation of a KSUID object
 func Parse(s string) (KSUID, error) {
 	if len(s) != stringEncodedLength {
-		return Nil, errStrSize
+		return Nil, &ParseError{Input: s, Offset: len(s), Length: stringEncodedLength, Err: ErrInvalidLength}
 	}
 
 	src := [stringEncodedLength]byte{}
@@ -186,7 +268,10 @@ func Parse(s string) (KSUID, error) {
 	copy(src[:], s[:])
 
 	if err := fastDecodeBase62(dst[:], src[:]); err != nil {
-		return Nil, errStrValue
+		if e, ok := err.(*ParseError); ok {
+			e.Input = s
+		}
+		return Nil, err
 	}
 
 	return FromBytes(dst[:])
@@ -206,6 +291,29 @@ func timeToCorrectedUTCTimestamp(t time.Time) uint64 {
 	return uint64(t.UnixNano() - epochStamp)
 }
 
+// checkedTimeToCorrectedUTCTimestamp is like timeToCorrectedUTCTimestamp but
+// returns ErrTimeOutOfRange instead of wrapping around when t is not within
+// MinTime and MaxTime.
+func checkedTimeToCorrectedUTCTimestamp(t time.Time) (uint64, error) {
+	if t.Before(MinTime) || t.After(MaxTime) {
+		return 0, ErrTimeOutOfRange
+	}
+	return timeToCorrectedUTCTimestamp(t), nil
+}
+
+// clampedTimeToCorrectedUTCTimestamp is like timeToCorrectedUTCTimestamp but
+// returns the closest timestamp when t is not within MinTime and MaxTime.
+func clampedTimeToCorrectedUTCTimestamp(t time.Time) uint64 {
+	switch {
+	case t.Before(MinTime):
+		return 0
+	case t.After(MaxTime):
+		return maxTimestamp
+	default:
+		return timeToCorrectedUTCTimestamp(t)
+	}
+}
+
 func correctedUTCTimestampToTime(ts uint64) time.Time {
 	return time.Unix(0, int64(ts)+epochStamp)
 }
@@ -225,31 +333,117 @@ func NewRandom() (ksuid KSUID, err error) {
 	return NewRandomWithTime(time.Now())
 }
 
+// Generates a new KSUID with the timestamp t, or returns ErrTimeOutOfRange if
+// t is not within MinTime and MaxTime.
 func NewRandomWithTime(t time.Time) (ksuid KSUID, err error) {
+	ts, err := checkedTimeToCorrectedUTCTimestamp(t)
+	if err != nil {
+		return
+	}
+
+	if err = readRandom(ksuid[timestampLengthInBytes:]); err != nil {
+		ksuid = Nil // don't leak random bytes on error
+		return
+	}
+
+	binary.BigEndian.PutUint64(ksuid[:timestampLengthInBytes], ts)
+	return
+}
+
+// NewBatch generates n KSUIDs with the timestamp t, reading the randomness
+// for all of their payloads in a single call to the source of random bytes.
+//
+// The returned KSUIDs are sorted. Their payloads are independent random
+// values, so consecutive KSUIDs of a batch are not guaranteed to be distinct.
+// Programs which need a strictly increasing batch can use Generator.Fill
+// instead, and Fill skips sorting when the order does not matter.
+//
+// ErrNegativeCount is returned if n is negative, and ErrTimeOutOfRange if t is
+// not within MinTime and MaxTime.
+func NewBatch(n int, t time.Time) ([]KSUID, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+
+	ids := make([]KSUID, n)
+
+	if err := fillRandomWithTime(ids, t); err != nil {
+		return nil, err
+	}
+
+	Sort(ids)
+	return ids, nil
+}
+
+// Fill generates KSUIDs with the current time into all elements of dst,
+// reading the randomness for all of their payloads in a single call to the
+// source of random bytes.
+//
+// The KSUIDs are not sorted, programs that need them in order can use Sort,
+// NewBatch or Generator.Fill instead. On error, dst is left unmodified.
+func Fill(dst []KSUID) error {
+	return fillRandomWithTime(dst, time.Now())
+}
+
+func fillRandomWithTime(dst []KSUID, t time.Time) error {
+	ts, err := checkedTimeToCorrectedUTCTimestamp(t)
+	if err != nil || len(dst) == 0 {
+		return err
+	}
+
+	payloads := make([]byte, len(dst)*payloadLengthInBytes)
+
+	if err := readRandomBulk(payloads); err != nil {
+		return err
+	}
+
+	for i := range dst {
+		binary.BigEndian.PutUint64(dst[i][:timestampLengthInBytes], ts)
+		copy(dst[i][timestampLengthInBytes:], payloads[i*payloadLengthInBytes:])
+	}
+
+	return nil
+}
+
+// readRandom fills b with bytes read from the global source of randomness, b
+// must not be longer than a KSUID payload.
+func readRandom(b []byte) (err error) {
+	if rander == rand.Reader {
+		// The default source is safe for concurrent use, so bytes are served
+		// from per-P batches instead of contending on the global mutex.
+		return readRandomBatch(b)
+	}
+
 	// Go's default random number generators are not safe for concurrent use by
 	// multiple goroutines, the use of the rander and randBuffer are explicitly
 	// synchronized here.
 	randMutex.Lock()
 
 	_, err = io.ReadAtLeast(rander, randBuffer[:], len(randBuffer))
-	copy(ksuid[timestampLengthInBytes:], randBuffer[:])
+	copy(b, randBuffer[:])
 
 	randMutex.Unlock()
+	return
+}
 
-	if err != nil {
-		ksuid = Nil // don't leak random bytes on error
+// readRandomBulk fills b with bytes read from the global source of randomness
+// in a single call, b may be of any length.
+func readRandomBulk(b []byte) (err error) {
+	if rander == rand.Reader {
+		_, err = io.ReadFull(rand.Reader, b)
 		return
 	}
 
-	ts := timeToCorrectedUTCTimestamp(t)
-	binary.BigEndian.PutUint64(ksuid[:timestampLengthInBytes], ts)
+	randMutex.Lock()
+	_, err = io.ReadFull(rander, b)
+	randMutex.Unlock()
 	return
 }
 
 // Constructs a KSUID from constituent parts
 func FromParts(t time.Time, payload []byte) (KSUID, error) {
 	if len(payload) != payloadLengthInBytes {
-		return Nil, errPayloadSize
+		return Nil, lengthError(payload, payloadLengthInBytes, ErrInvalidPayloadLength)
 	}
 
 	var ksuid KSUID
@@ -262,6 +456,17 @@ func FromParts(t time.Time, payload []byte) (KSUID, error) {
 	return ksuid, nil
 }
 
+// Constructs a KSUID from constituent parts.
+// Same behavior as FromParts, but returns ErrTimeOutOfRange if t is not
+// within MinTime and MaxTime instead of producing a KSUID with a wrapped
+// around timestamp.
+func FromPartsChecked(t time.Time, payload []byte) (KSUID, error) {
+	if _, err := checkedTimeToCorrectedUTCTimestamp(t); err != nil {
+		return Nil, err
+	}
+	return FromParts(t, payload)
+}
+
 // Constructs a KSUID from constituent parts.
 // Same behavior as FromParts, but returns a Nil KSUID on error.
 func FromPartsOrNil(t time.Time, payload []byte) KSUID {
@@ -272,12 +477,42 @@ func FromPartsOrNil(t time.Time, payload []byte) KSUID {
 	return ksuid
 }
 
+// MinAt returns the smallest KSUID with the timestamp t, which is the
+// inclusive lower bound of all KSUIDs generated at t.
+//
+// Times before MinTime or after MaxTime are clamped to MinTime and MaxTime.
+func MinAt(t time.Time) KSUID {
+	return makeUint96(0, 0).ksuid(clampedTimeToCorrectedUTCTimestamp(t))
+}
+
+// MaxAt returns the highest KSUID with the timestamp t, which is the
+// inclusive upper bound of all KSUIDs generated at t.
+//
+// Times before MinTime or after MaxTime are clamped to MinTime and MaxTime.
+// The result is lower than Max: KSUIDs with timestamps above the timestamp of
+// MaxTime, like Max, have times which overflow and are never part of a range.
+func MaxAt(t time.Time) KSUID {
+	return makeUint96(math.MaxUint32, math.MaxUint64).ksuid(clampedTimeToCorrectedUTCTimestamp(t))
+}
+
+// RangeFor returns the inclusive bounds of all KSUIDs generated between start
+// and end, both included.
+//
+// Because both the binary and string representations of KSUIDs sort in the
+// same order, the bounds can be used to scan a sorted column of either form:
+//
+//	lo, hi := ksuid.RangeFor(start, end)
+//	rows, err := db.Query(`SELECT ... WHERE id BETWEEN $1 AND $2`, lo, hi)
+func RangeFor(start, end time.Time) (lo, hi KSUID) {
+	return MinAt(start), MaxAt(end)
+}
+
 // Constructs a KSUID from a 20-byte binary representation
 func FromBytes(b []byte) (KSUID, error) {
 	var ksuid KSUID
 
 	if len(b) != byteLength {
-		return Nil, errSize
+		return Nil, lengthError(b, byteLength, ErrInvalidLength)
 	}
 
 	copy(ksuid[:], b)
@@ -311,9 +546,14 @@ func Compare(a, b KSUID) int {
 	return bytes.Compare(a[:], b[:])
 }
 
-// Sorts the given slice of KSUIDs
+// Sorts the given slice of KSUIDs. Large slices are sorted with RadixSort,
+// which allocates a temporary slice of the same length.
 func Sort(ids []KSUID) {
-	quickSort(ids, 0, len(ids)-1)
+	if len(ids) > radixSortThreshold {
+		RadixSort(ids, nil)
+	} else {
+		introSort(ids, 2*bits.Len(uint(len(ids))))
+	}
 }
 
 // IsSorted checks whether a slice of KSUIDs is sorted
@@ -330,28 +570,6 @@ func IsSorted(ids []KSUID) bool {
 	return true
 }
 
-func quickSort(a []KSUID, lo int, hi int) {
-	if lo < hi {
-		pivot := a[hi]
-		i := lo - 1
-
-		for j, n := lo, hi; j != n; j++ {
-			if bytes.Compare(a[j][:], pivot[:]) < 0 {
-				i++
-				a[i], a[j] = a[j], a[i]
-			}
-		}
-
-		i++
-		if bytes.Compare(a[hi][:], a[i][:]) < 0 {
-			a[i], a[hi] = a[hi], a[i]
-		}
-
-		quickSort(a, lo, i-1)
-		quickSort(a, i+1, hi)
-	}
-}
-
 // Next returns the next KSUID after id.
 func (id KSUID) Next() KSUID {
 	zero := makeUint96(0, 0)
@@ -3,8 +3,10 @@ package ksuid
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -64,7 +66,7 @@ func TestPadding(t *testing.T) {
 
 func TestParse(t *testing.T) {
 	_, err := Parse("123")
-	if err != errStrSize {
+	if !errors.Is(err, ErrInvalidLength) {
 		t.Fatal("Expected Parsing a 3-char string to return an error")
 	}
 
@@ -100,17 +102,124 @@ func TestParse(t *testing.T) {
 
 func TestIssue25(t *testing.T) {
 	// https://github.com/segmentio/ksuid/issues/25
-	for _, s := range []string{
-		"aaaaaaaaaaaaaaaaaaaaaaaaaaa",
-		"aWgEPTl1tmebfsQzFP4bxwgy80!",
+	for _, test := range []struct {
+		s   string
+		err error
+	}{
+		{"aaaaaaaaaaaaaaaaaaaaaaaaaaa", ErrOverflow},
+		{"aWgEPTl1tmebfsQzFP4bxwgy80!", ErrInvalidCharacter},
 	} {
-		_, err := Parse(s)
-		if err != errStrValue {
+		_, err := Parse(test.s)
+		if !errors.Is(err, test.err) {
 			t.Error("invalid KSUID representations cannot be successfully parsed, got err =", err)
 		}
 	}
 }
 
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		scenario string
+		function func() error
+		err      error
+		offset   int
+		length   int
+		char     byte
+	}{
+		{
+			scenario: "Parse with an invalid length",
+			function: func() error { _, err := Parse("0ujtsYcgvSTl8PAuAdqWYSMnLO"); return err },
+			err:      ErrInvalidLength,
+			offset:   26,
+			length:   27,
+		},
+		{
+			scenario: "Parse with an invalid character",
+			function: func() error { _, err := Parse("0ujtsYcgvSTl8-AuAdqWYSMnLOv"); return err },
+			err:      ErrInvalidCharacter,
+			offset:   13,
+			char:     '-',
+		},
+		{
+			scenario: "Parse with an overflowing value",
+			function: func() error { _, err := Parse("zzzzzzzzzzzzzzzzzzzzzzzzzzz"); return err },
+			err:      ErrOverflow,
+		},
+		{
+			scenario: "FromBytes with an invalid length",
+			function: func() error { _, err := FromBytes(make([]byte, 19)); return err },
+			err:      ErrInvalidLength,
+			offset:   19,
+			length:   20,
+		},
+		{
+			scenario: "FromParts with an invalid payload length",
+			function: func() error { _, err := FromParts(time.Now(), make([]byte, 16)); return err },
+			err:      ErrInvalidPayloadLength,
+			offset:   16,
+			length:   12,
+		},
+		{
+			scenario: "Scan with an invalid length",
+			function: func() error { var id KSUID; return id.Scan("0ujtsYcgvSTl8PAuAdqWYSMnLOv0") },
+			err:      ErrInvalidLength,
+			offset:   28,
+		},
+		{
+			scenario: "Scan with an invalid character",
+			function: func() error { var id KSUID; return id.Scan([]byte("_ujtsYcgvSTl8PAuAdqWYSMnLOv")) },
+			err:      ErrInvalidCharacter,
+			char:     '_',
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			err := test.function()
+
+			if !errors.Is(err, test.err) {
+				t.Fatal("bad error:", err)
+			}
+
+			var e *ParseError
+			if !errors.As(err, &e) {
+				t.Fatalf("the error is not a *ParseError: %T", err)
+			}
+
+			if e.Offset != test.offset {
+				t.Error("bad offset:", e.Offset, "!=", test.offset)
+			}
+
+			if e.Length != test.length {
+				t.Error("bad length:", e.Length, "!=", test.length)
+			}
+
+			if e.Char != test.char {
+				t.Errorf("bad character: %q != %q", e.Char, test.char)
+			}
+		})
+	}
+}
+
+func TestParseErrorString(t *testing.T) {
+	_, err := Parse("0ujtsYcgvSTl8-AuAdqWYSMnLOv")
+
+	const expected = `ksuid: invalid character '-' at offset 13 of "0ujtsYcgvSTl8-AuAdqWYSMnLOv": Valid encoded KSUIDs only contain characters from the alphabet of their encoding`
+
+	if s := err.Error(); s != expected {
+		t.Error(s)
+	}
+}
+
+func TestParseErrorLengthString(t *testing.T) {
+	_, err := ParseWith(Hex, "00")
+
+	const expected = `ksuid: cannot parse "00" of length 2, expected 40: Valid KSUIDs have the length of their binary representation or of their text encoding`
+
+	if s := err.Error(); s != expected {
+		t.Error(s)
+	}
+}
+
 func TestEncodeAndDecode(t *testing.T) {
 	x := New()
 	builtFromEncodedString, err := Parse(x.String())
@@ -317,6 +426,207 @@ func TestGetTimestamp(t *testing.T) {
 	}
 }
 
+func TestSetRand(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	SetRand(bytes.NewReader(payload))
+	defer SetRand(nil)
+
+	id, err := NewRandom()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(id.Payload(), payload) {
+		t.Error("the payload was not read from the custom source:", id.Payload())
+	}
+}
+
+func TestNewBatch(t *testing.T) {
+	now := time.Now()
+
+	ids, err := NewBatch(1000, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 1000 {
+		t.Fatal("bad number of KSUIDs:", len(ids))
+	}
+
+	if !IsSorted(ids) {
+		t.Error("the batch of KSUIDs is not sorted")
+	}
+
+	for _, id := range ids {
+		if !id.Time().Equal(now) {
+			t.Fatal("bad KSUID time:", id.Time(), "!=", now)
+		}
+	}
+
+	if _, err := NewBatch(-1, now); !errors.Is(err, ErrNegativeCount) {
+		t.Error("bad error for a negative number of KSUIDs:", err)
+	}
+}
+
+func TestFill(t *testing.T) {
+	ids := make([]KSUID, 1000)
+
+	if err := Fill(ids); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[KSUID]struct{}, len(ids))
+
+	for _, id := range ids {
+		if _, dupe := seen[id]; dupe {
+			t.Fatal("duplicate KSUID found:", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+func TestFillWithCustomRand(t *testing.T) {
+	payloads := make([]byte, 3*payloadLengthInBytes)
+	for i := range payloads {
+		payloads[i] = byte(i)
+	}
+
+	SetRand(bytes.NewReader(payloads))
+	defer SetRand(nil)
+
+	ids := make([]KSUID, 3)
+
+	if err := Fill(ids); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range ids {
+		if !bytes.Equal(id.Payload(), payloads[i*payloadLengthInBytes:(i+1)*payloadLengthInBytes]) {
+			t.Errorf("bad payload at index %d: %v", i, id.Payload())
+		}
+	}
+
+	if err := Fill(ids); err == nil {
+		t.Error("no error returned after exhausting the source of random bytes")
+	}
+}
+
+func TestFillWithFastRander(t *testing.T) {
+	SetRand(FastRander)
+	defer SetRand(nil)
+
+	ids := make([]KSUID, 1000)
+
+	if err := Fill(ids); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[KSUID]struct{}, len(ids))
+
+	for _, id := range ids {
+		if _, dupe := seen[id]; dupe {
+			t.Fatal("duplicate KSUID found:", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+func TestMinMaxAt(t *testing.T) {
+	now := time.Now()
+
+	min := MinAt(now)
+	max := MaxAt(now)
+
+	if !min.Time().Equal(now) || !max.Time().Equal(now) {
+		t.Error("bad bound times:", min.Time(), max.Time(), "!=", now)
+	}
+
+	for i := 0; i != 100; i++ {
+		id, _ := NewRandomWithTime(now)
+
+		if Compare(min, id) > 0 || Compare(id, max) > 0 {
+			t.Errorf("%s is not within the bounds [%s, %s]", id, min, max)
+		}
+		if min.String() > id.String() || id.String() > max.String() {
+			t.Errorf("%q is not within the string bounds [%q, %q]", id, min, max)
+		}
+	}
+
+	if max.Next() != MinAt(now.Add(time.Nanosecond)) {
+		t.Error("the bounds of consecutive nanoseconds are not adjacent:", max, MinAt(now.Add(time.Nanosecond)))
+	}
+}
+
+func TestRangeFor(t *testing.T) {
+	start := time.Now()
+	end := start.Add(time.Minute)
+
+	lo, hi := RangeFor(start, end)
+
+	tests := []struct {
+		time   time.Time
+		within bool
+	}{
+		{start.Add(-time.Nanosecond), false},
+		{start, true},
+		{start.Add(time.Second), true},
+		{end, true},
+		{end.Add(time.Nanosecond), false},
+	}
+
+	for _, test := range tests {
+		id, _ := NewRandomWithTime(test.time)
+		within := Compare(lo, id) <= 0 && Compare(id, hi) <= 0
+
+		if within != test.within {
+			t.Errorf("KSUID generated at %s: within=%t, expected %t", test.time, within, test.within)
+		}
+	}
+}
+
+func TestTimeOutOfRange(t *testing.T) {
+	payload := make([]byte, payloadLengthInBytes)
+
+	for _, tm := range []time.Time{
+		MinTime.Add(-time.Nanosecond),
+		time.Unix(0, 0),
+		MaxTime.Add(time.Nanosecond),
+		time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := FromPartsChecked(tm, payload); err != ErrTimeOutOfRange {
+			t.Errorf("FromPartsChecked(%s): bad error: %v", tm, err)
+		}
+		if _, err := NewRandomWithTime(tm); err != ErrTimeOutOfRange {
+			t.Errorf("NewRandomWithTime(%s): bad error: %v", tm, err)
+		}
+		if _, err := NewBatch(1, tm); err != ErrTimeOutOfRange {
+			t.Errorf("NewBatch(%s): bad error: %v", tm, err)
+		}
+	}
+
+	for _, tm := range []time.Time{MinTime, MaxTime} {
+		id, err := FromPartsChecked(tm, payload)
+		if err != nil {
+			t.Errorf("FromPartsChecked(%s): %v", tm, err)
+		} else if !id.Time().Equal(tm) {
+			t.Errorf("bad time: %s != %s", id.Time(), tm)
+		}
+	}
+
+	if MinAt(MinTime.Add(-time.Hour)) != Nil {
+		t.Error("MinAt did not clamp the time before MinTime")
+	}
+
+	if MaxAt(MaxTime.Add(time.Hour)) != MaxAt(MaxTime) {
+		t.Error("MaxAt did not clamp the time after MaxTime")
+	}
+
+	if _, hi := RangeFor(MinTime, MaxTime.Add(1)); !hi.Time().Equal(MaxTime) {
+		t.Error("bad time of the upper bound of a range ending after MaxTime:", hi.Time())
+	}
+}
+
 func testPrevNext(t *testing.T, id, prev, next KSUID) {
 	id1 := id.Prev()
 	id2 := id.Next()
@@ -391,6 +701,56 @@ func BenchmarkNew(b *testing.B) {
 	})
 }
 
+func BenchmarkNewBatch(b *testing.B) {
+	const n = 1000
+
+	b.Run("with New", func(b *testing.B) {
+		ids := make([]KSUID, n)
+		for i := 0; i != b.N; i++ {
+			for j := range ids {
+				ids[j] = New()
+			}
+		}
+	})
+	b.Run("with Fill", func(b *testing.B) {
+		ids := make([]KSUID, n)
+		for i := 0; i != b.N; i++ {
+			Fill(ids)
+		}
+	})
+	b.Run("with NewBatch", func(b *testing.B) {
+		now := time.Now()
+		for i := 0; i != b.N; i++ {
+			NewBatch(n, now)
+		}
+	})
+}
+
+// BenchmarkNewParallel measures how generating KSUIDs scales with the number
+// of cores, by running with GOMAXPROCS set to 1, 2, 4 and 8.
+func BenchmarkNewParallel(b *testing.B) {
+	for _, procs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("with crypto rand x%d", procs), func(b *testing.B) {
+			SetRand(nil)
+			benchmarkNewParallel(b, procs)
+		})
+		b.Run(fmt.Sprintf("with math rand x%d", procs), func(b *testing.B) {
+			SetRand(FastRander)
+			defer SetRand(nil)
+			benchmarkNewParallel(b, procs)
+		})
+	}
+}
+
+func benchmarkNewParallel(b *testing.B, procs int) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			New()
+		}
+	})
+}
+
 // TestTimeMonotonicity verifies timestamps are monotonically increasing
 func TestTimeMonotonicity(t *testing.T) {
 	count := 10000
@@ -0,0 +1,117 @@
+package ksuid
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Classic KSUIDs, as generated by github.com/segmentio/ksuid, are also 20
+// bytes long but use a different layout:
+//
+//	00-03 byte: uint32 BE UTC timestamp with second precision
+//	04-19 byte: random "payload"
+//
+// Both formats share the same epoch and the same base62 string encoding, so
+// they cannot be told apart by their shape alone.
+const (
+	legacyTimestampLengthInBytes = 4
+
+	// The future clock skew tolerated when deciding whether a timestamp is
+	// plausible in DetectFormat.
+	detectFormatClockSkew = 24 * time.Hour
+)
+
+// Format represents the layout of the 20 bytes of a KSUID.
+type Format int
+
+const (
+	// FormatUnknown is returned by DetectFormat when the value is not a valid
+	// KSUID in any of the known formats.
+	FormatUnknown Format = iota
+
+	// FormatKSUID is the layout of the KSUID type, with a 64 bits nanosecond
+	// timestamp and a 96 bits payload.
+	FormatKSUID
+
+	// FormatLegacy is the layout of classic KSUIDs, with a 32 bits second
+	// timestamp and a 128 bits payload.
+	FormatLegacy
+)
+
+// String satisfies the fmt.Stringer interface.
+func (f Format) String() string {
+	switch f {
+	case FormatKSUID:
+		return "ksuid"
+	case FormatLegacy:
+		return "legacy"
+	default:
+		return "unknown"
+	}
+}
+
+// FromLegacy converts the 20-byte binary representation of a classic KSUID to
+// a KSUID.
+//
+// The time of the classic KSUID is preserved. Its 16 bytes payload is
+// truncated to the first 12 bytes, which preserves the sort order between
+// converted KSUIDs (but two classic KSUIDs which only differ in their last 4
+// bytes convert to the same KSUID).
+func FromLegacy(b []byte) (KSUID, error) {
+	if len(b) != byteLength {
+		return Nil, lengthError(b, byteLength, ErrInvalidLength)
+	}
+
+	// Both formats use the same epoch, the number of seconds only needs to be
+	// converted to nanoseconds.
+	secs := binary.BigEndian.Uint32(b[:legacyTimestampLengthInBytes])
+	ts := uint64(secs) * uint64(time.Second)
+
+	var ksuid KSUID
+	binary.BigEndian.PutUint64(ksuid[:timestampLengthInBytes], ts)
+	copy(ksuid[timestampLengthInBytes:], b[legacyTimestampLengthInBytes:])
+	return ksuid, nil
+}
+
+// ParseLegacy decodes the string-encoded representation of a classic KSUID
+// and converts it to a KSUID, see FromLegacy for details on the conversion.
+func ParseLegacy(s string) (KSUID, error) {
+	// The string encoding does not depend on the layout of the bytes.
+	raw, err := Parse(s)
+	if err != nil {
+		return Nil, err
+	}
+	return FromLegacy(raw[:])
+}
+
+// DetectFormat guesses whether the string-encoded KSUID s is a KSUID or a
+// classic KSUID, based on which of the two interpretations of its timestamp
+// is plausible (not in the future).
+//
+// Reading the timestamp of a classic KSUID as nanoseconds places it about 4.3
+// times further from the epoch than its real time, so classic KSUIDs generated
+// in the last three quarters of the time since the epoch are detected
+// reliably. Older classic KSUIDs have a plausible time in both formats and are
+// reported as FormatKSUID.
+func DetectFormat(s string) Format {
+	raw, err := Parse(s)
+	if err != nil {
+		return FormatUnknown
+	}
+	return detectFormat(raw, time.Now())
+}
+
+func detectFormat(raw KSUID, now time.Time) Format {
+	limit := now.Add(detectFormatClockSkew)
+
+	if raw.Timestamp() <= clampedTimeToCorrectedUTCTimestamp(limit) {
+		return FormatKSUID
+	}
+
+	secs := binary.BigEndian.Uint32(raw[:legacyTimestampLengthInBytes])
+	if uint64(secs)*uint64(time.Second) <= clampedTimeToCorrectedUTCTimestamp(limit) {
+		return FormatLegacy
+	}
+
+	return FormatUnknown
+}
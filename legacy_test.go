@@ -0,0 +1,107 @@
+package ksuid
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Classic KSUID taken from the documentation of github.com/segmentio/ksuid:
+//
+//	String: 0ujtsYcgvSTl8PAuAdqWYSMnLOv
+//	   Raw: 0669F7EFB5A1CD34B5F99D1154FB6853345C9735
+//	  Time: 2017-10-09 21:00:47 -0700 PDT
+const legacyString = "0ujtsYcgvSTl8PAuAdqWYSMnLOv"
+
+func TestParseLegacy(t *testing.T) {
+	id, err := ParseLegacy(legacyString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tm := time.Date(2017, 10, 10, 4, 0, 47, 0, time.UTC); !id.Time().Equal(tm) {
+		t.Error("bad time:", id.Time(), "!=", tm)
+	}
+
+	if p := strings.ToUpper(hex.EncodeToString(id.Payload())); p != "B5A1CD34B5F99D1154FB6853" {
+		t.Error("bad payload:", p)
+	}
+}
+
+func TestFromLegacy(t *testing.T) {
+	raw, _ := hex.DecodeString("0669F7EFB5A1CD34B5F99D1154FB6853345C9735")
+
+	id1, err := FromLegacy(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id2, _ := ParseLegacy(legacyString)
+
+	if id1 != id2 {
+		t.Error(id1, "!=", id2)
+	}
+
+	if _, err := FromLegacy(raw[:19]); !errors.Is(err, ErrInvalidLength) {
+		t.Error("bad error:", err)
+	}
+}
+
+func TestFromLegacyPreservesOrder(t *testing.T) {
+	raws := [][]byte{
+		{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0},
+		{0, 0, 0, 1, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		{0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{255, 255, 255, 255, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+
+	ids := make([]KSUID, len(raws))
+	for i, raw := range raws {
+		ids[i], _ = FromLegacy(raw)
+	}
+
+	if !IsSorted(ids) {
+		t.Error("converted KSUIDs are not sorted:", ids)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	legacy := func(tm time.Time) KSUID {
+		var raw KSUID
+		secs := uint32(tm.Unix() - epochStamp/int64(time.Second))
+		raw[0], raw[1], raw[2], raw[3] = byte(secs>>24), byte(secs>>16), byte(secs>>8), byte(secs)
+		copy(raw[4:], "0123456789ABCDEF")
+		return raw
+	}
+
+	tests := []struct {
+		raw    KSUID
+		format Format
+	}{
+		{MinAt(now.Add(-time.Hour)), FormatKSUID},
+		{MaxAt(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)), FormatKSUID},
+		{legacy(now.Add(-time.Hour)), FormatLegacy},
+		{legacy(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)), FormatLegacy},
+		{legacy(now.Add(30 * 24 * time.Hour)), FormatUnknown},
+		{Max, FormatUnknown},
+	}
+
+	for _, test := range tests {
+		if format := detectFormat(test.raw, now); format != test.format {
+			t.Errorf("%s: bad format: %s != %s", test.raw, format, test.format)
+		}
+	}
+
+	if format := DetectFormat("not a KSUID"); format != FormatUnknown {
+		t.Error("bad format for an invalid string:", format)
+	}
+
+	if format := DetectFormat(New().String()); format != FormatKSUID {
+		t.Error("bad format for a new KSUID:", format)
+	}
+}
@@ -0,0 +1,157 @@
+package ksuid
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPrefix is the error wrapped by a ParseError when a prefixed KSUID
+// does not start with the prefix of its type.
+var ErrInvalidPrefix = errors.New("Valid prefixed KSUIDs start with the prefix of their type")
+
+// Prefix is the interface implemented by the types declaring the prefix of
+// typed identifiers. The prefix is read from the zero-value of the type, so it
+// is usually implemented by an empty struct:
+//
+//	type spanPrefix struct{}
+//
+//	func (spanPrefix) Prefix() string { return "span_" }
+//
+//	type SpanID = ksuid.Prefixed[spanPrefix]
+type Prefix interface {
+	Prefix() string
+}
+
+// Prefixed is a KSUID which is represented as text with a prefix identifying
+// the type of the entity it refers to, like "span_0ujtsYcgvSTl8PAuAdqWYSMnLOv".
+//
+// Parsing a prefixed KSUID fails if the string does not start with the prefix
+// of P, which prevents identifiers of one type from being used in place of
+// another. The binary representation of a prefixed KSUID is the same as the
+// one of a KSUID.
+type Prefixed[P Prefix] KSUID
+
+// NewPrefixed generates a new prefixed KSUID. In the strange case that random
+// bytes can't be read, it will panic.
+func NewPrefixed[P Prefix]() Prefixed[P] {
+	return Prefixed[P](New())
+}
+
+// ParsePrefixed decodes a string-encoded representation of a prefixed KSUID.
+func ParsePrefixed[P Prefix](s string) (Prefixed[P], error) {
+	var p P
+	prefix := p.Prefix()
+
+	if len(s) < len(prefix) || s[:len(prefix)] != prefix {
+		return Prefixed[P](Nil), &ParseError{Input: s, Offset: prefixMismatch(s, prefix), Err: ErrInvalidPrefix}
+	}
+
+	id, err := Parse(s[len(prefix):])
+	if err != nil {
+		if e, ok := err.(*ParseError); ok {
+			e.Input = s
+			e.Offset += len(prefix)
+			if e.Length != 0 {
+				e.Length += len(prefix)
+			}
+		}
+		return Prefixed[P](Nil), err
+	}
+
+	return Prefixed[P](id), nil
+}
+
+// prefixMismatch returns the offset of the first byte of s which does not
+// match the prefix.
+func prefixMismatch(s, prefix string) int {
+	i := 0
+	for i < len(s) && i < len(prefix) && s[i] == prefix[i] {
+		i++
+	}
+	return i
+}
+
+// KSUID returns the KSUID of id, without its prefix.
+func (id Prefixed[P]) KSUID() KSUID {
+	return KSUID(id)
+}
+
+// Prefix returns the prefix of the type of id.
+func (id Prefixed[P]) Prefix() string {
+	var p P
+	return p.Prefix()
+}
+
+// IsNil returns true if this is a "nil" KSUID
+func (id Prefixed[P]) IsNil() bool {
+	return KSUID(id) == Nil
+}
+
+// Append appends the prefixed string representation of id to b, returning a
+// slice to a potentially larger memory area.
+func (id Prefixed[P]) Append(b []byte) []byte {
+	return KSUID(id).Append(append(b, id.Prefix()...))
+}
+
+// String-encoded representation with the prefix, that can be passed through
+// ParsePrefixed()
+func (id Prefixed[P]) String() string {
+	return string(id.Append(make([]byte, 0, len(id.Prefix())+stringEncodedLength)))
+}
+
+func (id Prefixed[P]) MarshalText() ([]byte, error) {
+	return id.Append(make([]byte, 0, len(id.Prefix())+stringEncodedLength)), nil
+}
+
+func (id Prefixed[P]) MarshalBinary() ([]byte, error) {
+	return KSUID(id).MarshalBinary()
+}
+
+func (id *Prefixed[P]) UnmarshalText(b []byte) error {
+	p, err := ParsePrefixed[P](string(b))
+	if err != nil {
+		return err
+	}
+	*id = p
+	return nil
+}
+
+func (id *Prefixed[P]) UnmarshalBinary(b []byte) error {
+	return (*KSUID)(id).UnmarshalBinary(b)
+}
+
+// Value converts the prefixed KSUID into a SQL driver value which can be used
+// to directly use the prefixed KSUID as parameter to a SQL query.
+func (id Prefixed[P]) Value() (driver.Value, error) {
+	if id.IsNil() {
+		return nil, nil
+	}
+	return id.String(), nil
+}
+
+// Scan implements the sql.Scanner interface. It supports converting from
+// a prefixed string, the 20-byte binary representation of a KSUID, or nil
+// into a prefixed KSUID value. Attempting to convert from another type will
+// return an error.
+func (id *Prefixed[P]) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*id = Prefixed[P](Nil)
+		return nil
+	case []byte:
+		if len(v) == byteLength {
+			return id.UnmarshalBinary(v)
+		}
+		return id.UnmarshalText(v)
+	case string:
+		p, err := ParsePrefixed[P](v)
+		if err != nil {
+			return err
+		}
+		*id = p
+		return nil
+	default:
+		return fmt.Errorf("Scan: unable to scan type %T into %T", v, id)
+	}
+}
@@ -0,0 +1,160 @@
+package ksuid
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type spanPrefix struct{}
+
+func (spanPrefix) Prefix() string { return "span_" }
+
+type tracePrefix struct{}
+
+func (tracePrefix) Prefix() string { return "trace_" }
+
+type spanID = Prefixed[spanPrefix]
+type traceID = Prefixed[tracePrefix]
+
+func TestPrefixed(t *testing.T) {
+	id := NewPrefixed[spanPrefix]()
+	s := id.String()
+
+	if s != "span_"+id.KSUID().String() {
+		t.Error("bad prefixed string:", s)
+	}
+
+	parsed, err := ParsePrefixed[spanPrefix](s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed != id {
+		t.Error(parsed, "!=", id)
+	}
+}
+
+func TestPrefixedErrors(t *testing.T) {
+	id := New()
+
+	tests := []struct {
+		s      string
+		err    error
+		offset int
+	}{
+		{"trace_" + id.String(), ErrInvalidPrefix, 0},
+		{"spa", ErrInvalidPrefix, 3},
+		{"spam_" + id.String(), ErrInvalidPrefix, 3},
+		{id.String(), ErrInvalidPrefix, 0},
+		{"span_" + id.String()[1:], ErrInvalidLength, 31},
+		{"span_0ujtsYcgvSTl8-AuAdqWYSMnLOv", ErrInvalidCharacter, 18},
+	}
+
+	for _, test := range tests {
+		_, err := ParsePrefixed[spanPrefix](test.s)
+
+		var e *ParseError
+		if !errors.As(err, &e) || !errors.Is(err, test.err) {
+			t.Errorf("%q: bad error: %v", test.s, err)
+			continue
+		}
+
+		if e.Input != test.s {
+			t.Errorf("%q: bad input: %q", test.s, e.Input)
+		}
+
+		if e.Offset != test.offset {
+			t.Errorf("%q: bad offset: %d != %d", test.s, e.Offset, test.offset)
+		}
+	}
+}
+
+func TestPrefixedJSON(t *testing.T) {
+	type span struct {
+		ID    spanID  `json:"id"`
+		Trace traceID `json:"trace"`
+	}
+
+	s1 := span{ID: NewPrefixed[spanPrefix](), Trace: NewPrefixed[tracePrefix]()}
+	s2 := span{}
+
+	b, err := json.Marshal(s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(b, &s2); err != nil {
+		t.Fatal(err)
+	}
+
+	if s1 != s2 {
+		t.Error(s1, "!=", s2)
+	}
+
+	// Swapping the identifiers must fail because of their prefixes.
+	swapped := `{"id":"` + s1.Trace.String() + `","trace":"` + s1.ID.String() + `"}`
+
+	if err := json.Unmarshal([]byte(swapped), &s2); !errors.Is(err, ErrInvalidPrefix) {
+		t.Error("bad error:", err)
+	}
+}
+
+func TestPrefixedSQL(t *testing.T) {
+	id1 := NewPrefixed[spanPrefix]()
+
+	v, err := id1.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, ok := v.(string); !ok || s != id1.String() {
+		t.Error("bad SQL value:", v)
+	}
+
+	if v, err := Prefixed[spanPrefix](Nil).Value(); err != nil || v != nil {
+		t.Error("bad SQL value for the nil KSUID:", v, err)
+	}
+
+	for _, src := range []interface{}{id1.String(), []byte(id1.String()), id1.KSUID().Bytes()} {
+		var id2 spanID
+
+		if err := id2.Scan(src); err != nil {
+			t.Errorf("%T: %v", src, err)
+		} else if id1 != id2 {
+			t.Errorf("%T: %s != %s", src, id1, id2)
+		}
+	}
+
+	var id3 spanID
+	if err := id3.Scan(id1.KSUID().String()); !errors.Is(err, ErrInvalidPrefix) {
+		t.Error("bad error when scanning a KSUID without prefix:", err)
+	}
+
+	if err := id3.Scan(nil); err != nil || !id3.IsNil() {
+		t.Error("scanning nil did not produce the nil KSUID:", id3, err)
+	}
+}
+
+func TestPrefixedAppendAllocs(t *testing.T) {
+	id := NewPrefixed[spanPrefix]()
+	b := make([]byte, 0, 64)
+
+	if n := testing.AllocsPerRun(100, func() { id.Append(b[:0]) }); n != 0 {
+		t.Error("Append allocated memory:", n)
+	}
+
+	s := id.String()
+
+	if n := testing.AllocsPerRun(100, func() { ParsePrefixed[spanPrefix](s) }); n != 0 {
+		t.Error("ParsePrefixed allocated memory:", n)
+	}
+}
+
+func BenchmarkParsePrefixed(b *testing.B) {
+	s := NewPrefixed[spanPrefix]().String()
+
+	for i := 0; i != b.N; i++ {
+		ParsePrefixed[spanPrefix](s)
+	}
+}
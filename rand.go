@@ -5,10 +5,11 @@ import (
 	"encoding/binary"
 	"io"
 	"math/rand"
+	"sync"
 )
 
 // FastRander is an io.Reader that uses math/rand and is optimized for
-// generating 16 bytes KSUID payloads. It is intended to be used as a
+// generating 12 bytes KSUID payloads. It is intended to be used as a
 // performance improvements for programs that have no need for
 // cryptographically secure KSUIDs and are generating a lot of them.
 var FastRander = newRBG()
@@ -48,9 +49,58 @@ type randSourceReader struct {
 }
 
 func (r *randSourceReader) Read(b []byte) (int, error) {
-	// optimized for generating 16 bytes payloads
-	val := r.source.Uint64()
-	binary.LittleEndian.PutUint32(b[:4], uint32(val))       // Use lower 32 bits
-	binary.LittleEndian.PutUint64(b[4:], r.source.Uint64()) // Generate new 64 bits
-	return 16, nil
+	n := len(b)
+
+	// optimized for generating 12 bytes payloads, 8 bytes are generated at a
+	// time and the remaining bytes are taken from one last 64 bits value.
+	for len(b) >= 8 {
+		binary.LittleEndian.PutUint64(b, r.source.Uint64())
+		b = b[8:]
+	}
+
+	if len(b) != 0 {
+		c := [8]byte{}
+		binary.LittleEndian.PutUint64(c[:], r.source.Uint64())
+		copy(b, c[:])
+	}
+
+	return n, nil
+}
+
+// randBatchLength is the number of random bytes read at once from
+// crypto/rand.Reader to serve the payloads of many KSUIDs.
+const randBatchLength = 256 * payloadLengthInBytes
+
+// randBatch holds random bytes which have not been used yet.
+type randBatch struct {
+	buf [randBatchLength]byte
+	off int
+}
+
+// randBatches is a pool of random batches, because sync.Pool caches values per
+// P (processor) the batches are refilled in bulk without any contention
+// between goroutines generating KSUIDs on different cores.
+//
+// Batches are never shared, bytes handed out from a batch are consumed and
+// will not be returned again.
+var randBatches = sync.Pool{
+	New: func() interface{} { return &randBatch{off: randBatchLength} },
+}
+
+// readRandomBatch fills b with bytes from a batch of random bytes read from
+// crypto/rand.Reader.
+func readRandomBatch(b []byte) error {
+	batch := randBatches.Get().(*randBatch)
+
+	if len(b) > len(batch.buf)-batch.off {
+		if _, err := io.ReadFull(cryptoRand.Reader, batch.buf[:]); err != nil {
+			randBatches.Put(batch)
+			return err
+		}
+		batch.off = 0
+	}
+
+	batch.off += copy(b, batch.buf[batch.off:])
+	randBatches.Put(batch)
+	return nil
 }
@@ -2,17 +2,29 @@ package ksuid
 
 import (
 	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 )
 
+// ErrMalformedSet is the error wrapped by the errors returned when decoding a
+// compressed set which was not produced by this package.
+var ErrMalformedSet = errors.New("Valid compressed sets of KSUIDs only contain records in the format written by this package")
+
 // CompressedSet is an immutable data type which stores a set of KSUIDs.
 type CompressedSet []byte
 
 // Iter returns an iterator that produces all KSUIDs in the set.
 func (set CompressedSet) Iter() CompressedSetIter {
-	return CompressedSetIter{
+	it := CompressedSetIter{
 		content: []byte(set),
 	}
+	it.index, it.indexed = parseSetIndex(it.content)
+	return it
 }
 
 // String satisfies the fmt.Stringer interface, returns a human-readable string
@@ -35,6 +47,128 @@ func (set CompressedSet) GoString() string {
 	return b.String()
 }
 
+// MarshalJSON satisfies the json.Marshaler interface, the set is encoded as
+// an array of base62 strings. See CompactCompressedSet for a more compact
+// representation.
+func (set CompressedSet) MarshalJSON() ([]byte, error) {
+	b := make([]byte, 0, 2+(stringEncodedLength+3)*len(set)/8)
+	b = append(b, '[')
+
+	it := set.Iter()
+	for i := 0; it.Next(); i++ {
+		if i != 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '"')
+		b = it.KSUID.Append(b)
+		b = append(b, '"')
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(b, ']'), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface. It accepts both
+// the array and the base64 string representations of sets.
+func (set *CompressedSet) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+
+	switch {
+	case bytes.Equal(b, []byte("null")):
+		*set = nil
+		return nil
+
+	case len(b) != 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		return set.UnmarshalBinary(data)
+
+	default:
+		var ids []KSUID
+		if err := json.Unmarshal(b, &ids); err != nil {
+			return err
+		}
+		*set = Compress(ids...)
+		return nil
+	}
+}
+
+func (set CompressedSet) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), set...), nil
+}
+
+// UnmarshalBinary satisfies the encoding.BinaryUnmarshaler interface, the
+// data is validated before being copied to the set.
+func (set *CompressedSet) UnmarshalBinary(b []byte) error {
+	if err := CompressedSet(b).Validate(); err != nil {
+		return err
+	}
+	*set = append(CompressedSet(nil), b...)
+	return nil
+}
+
+// Value converts the set into a SQL driver value which can be used to
+// directly use the set as parameter to a SQL query. Sets are stored in their
+// binary representation, empty sets are stored as NULL.
+func (set CompressedSet) Value() (driver.Value, error) {
+	if len(set) == 0 {
+		return nil, nil
+	}
+	return []byte(set), nil
+}
+
+// Scan implements the sql.Scanner interface. It supports converting from
+// the binary representation of a set as []byte or string, or nil into a set
+// value. Attempting to convert from another type will return an error.
+func (set *CompressedSet) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*set = nil
+		return nil
+	case []byte:
+		return set.UnmarshalBinary(v)
+	case string:
+		return set.UnmarshalBinary([]byte(v))
+	default:
+		return fmt.Errorf("Scan: unable to scan type %T into CompressedSet", v)
+	}
+}
+
+// CompactCompressedSet is a CompressedSet which is encoded to JSON as a
+// base64 string of its binary representation, rather than an array of base62
+// strings. It is usually much smaller, but cannot be read by humans.
+//
+// The type is intended to be used in place of CompressedSet in the fields of
+// structs encoded to JSON:
+//
+//	type Trace struct {
+//		Spans ksuid.CompactCompressedSet `json:"spans"`
+//	}
+type CompactCompressedSet CompressedSet
+
+// MarshalJSON satisfies the json.Marshaler interface.
+func (set CompactCompressedSet) MarshalJSON() ([]byte, error) {
+	if err := CompressedSet(set).Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(set))
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface. It accepts both
+// the array and the base64 string representations of sets.
+func (set *CompactCompressedSet) UnmarshalJSON(b []byte) error {
+	return (*CompressedSet)(set).UnmarshalJSON(b)
+}
+
 func (set CompressedSet) writeTo(b *bytes.Buffer) {
 	a := [27]byte{}
 
@@ -69,94 +203,314 @@ func AppendCompressed(set []byte, ids ...KSUID) CompressedSet {
 		if !IsSorted(ids) {
 			Sort(ids)
 		}
-		one := makeUint96(0, 1)
 
+		e := setEncoder{buf: set}
+
+		for _, id := range ids {
+			e.add(id)
+		}
+
+		e.flush()
+		set = e.buf
+	}
+	return CompressedSet(set)
+}
+
+// Union returns a compressed set of the KSUIDs found in a or b.
+//
+// The set operations stream the content of both sets in merge order, so they
+// expect a and b to yield their KSUIDs in ascending order, which is the case
+// for sets built by a single call to Compress or AppendCompressed.
+func Union(a, b CompressedSet) CompressedSet {
+	return mergeCompressed(a, b, true, true, true)
+}
+
+// Intersect returns a compressed set of the KSUIDs found in both a and b.
+func Intersect(a, b CompressedSet) CompressedSet {
+	return mergeCompressed(a, b, false, true, false)
+}
+
+// Difference returns a compressed set of the KSUIDs found in a but not in b.
+func Difference(a, b CompressedSet) CompressedSet {
+	return mergeCompressed(a, b, true, false, false)
+}
+
+// SymmetricDifference returns a compressed set of the KSUIDs found in either
+// a or b, but not in both.
+func SymmetricDifference(a, b CompressedSet) CompressedSet {
+	return mergeCompressed(a, b, true, false, true)
+}
+
+// mergeCompressed walks the iterators of a and b in merge order, encoding the
+// KSUIDs found only in a, in both sets, or only in b to the returned set.
+func mergeCompressed(a, b CompressedSet, onlyA, both, onlyB bool) CompressedSet {
+	e := setEncoder{}
+	itA, itB := a.Iter(), b.Iter()
+	okA, okB := itA.Next(), itB.Next()
+
+	for (okA && (okB || onlyA)) || (okB && onlyB) {
+		switch {
+		case !okB || (okA && less(&itA.KSUID, &itB.KSUID)):
+			if onlyA {
+				e.add(itA.KSUID)
+			}
+			okA = itA.Next()
+
+		case !okA || less(&itB.KSUID, &itA.KSUID):
+			if onlyB {
+				e.add(itB.KSUID)
+			}
+			okB = itB.Next()
+
+		default:
+			if both {
+				e.add(itA.KSUID)
+			}
+			okA, okB = itA.Next(), itB.Next()
+		}
+	}
+
+	e.flush()
+	return CompressedSet(e.buf)
+}
+
+// setEncoder incrementally encodes KSUIDs in the compressed set format. The
+// KSUIDs must be given in ascending order, duplicates are skipped.
+type setEncoder struct {
+	buf []byte
+
+	// When interval is not zero, a raw KSUID is written to the set after
+	// every interval KSUIDs, and its offset in buf is recorded in restarts.
+	interval uint64
+	restarts []uint32
+	restart  uint64
+	count    uint64
+
+	started   bool
+	timestamp uint64
+	lastKSUID KSUID
+	lastValue uint96
+
+	// Number of KSUIDs following the last one written to buf, and which
+	// belong to the payload range being built.
+	seqlength uint64
+}
+
+// add appends id to the set being encoded. The last KSUIDs may be held back
+// to be encoded as a payload range, flush must be called to write them.
+func (e *setEncoder) add(id KSUID) {
+	if e.started && id == e.lastKSUID {
+		return
+	}
+
+	t := id.Timestamp()
+	v := uint96Payload(id)
+	e.count++
+
+	switch {
+	case !e.started:
 		// The first KSUID is always written to the set, this is the starting
 		// point for all deltas.
-		set = append(set, byte(rawKSUID))
-		set = append(set, ids[0][:]...)
+		e.writeRaw(id, t)
+		e.started = true
 
-		timestamp := ids[0].Timestamp()
-		lastKSUID := ids[0]
-		lastValue := uint96Payload(ids[0])
+	case t == e.timestamp && incr96(e.lastValue) == v:
+		e.seqlength++
 
-		for i := 1; i != len(ids); i++ {
-			id := ids[i]
+	case e.interval != 0 && e.count-e.restart > e.interval:
+		e.flush()
+		e.writeRaw(id, t)
 
-			if id == lastKSUID {
-				continue
-			}
+	case t != e.timestamp:
+		e.flush()
 
-			t := id.Timestamp()
-			v := uint96Payload(id)
+		d := t - e.timestamp
+		n := varintLength64(d)
 
-			if t != timestamp {
-				d := t - timestamp
-				n := varintLength64(d)
+		e.buf = append(e.buf, timeDelta|byte(n))
+		e.buf = appendVarint64(e.buf, d, n)
+		e.buf = append(e.buf, id[timestampLengthInBytes:]...)
 
-				set = append(set, timeDelta|byte(n))
-				set = appendVarint64(set, d, n)
-				set = append(set, id[timestampLengthInBytes:]...)
+		e.timestamp = t
 
-				timestamp = t
-			} else {
-				d := sub96(v, lastValue)
+	default:
+		e.flush()
 
-				if d != one {
-					n := varintLength96(d)
+		d := sub96(v, e.lastValue)
+		n := varintLength96(d)
 
-					set = append(set, payloadDelta|byte(n))
-					set = appendVarint96(set, d, n)
-				} else {
-					l, c := rangeLength(ids[i+1:], t, id, v)
-					m := uint64(l + 1)
-					n := varintLength64(m)
+		e.buf = append(e.buf, payloadDelta|byte(n))
+		e.buf = appendVarint96(e.buf, d, n)
+	}
 
-					set = append(set, payloadRange|byte(n))
-					set = appendVarint64(set, m, n)
+	e.lastKSUID = id
+	e.lastValue = v
+}
 
-					i += c
-					id = ids[i]
-					v = uint96Payload(id)
-				}
-			}
+// writeRaw writes id to the set as a restart point, which does not depend on
+// the KSUIDs before it.
+func (e *setEncoder) writeRaw(id KSUID, t uint64) {
+	if e.interval != 0 {
+		e.restarts = append(e.restarts, uint32(len(e.buf)))
+		e.restart = e.count
+	}
 
-			lastKSUID = id
-			lastValue = v
-		}
+	e.buf = append(e.buf, byte(rawKSUID))
+	e.buf = append(e.buf, id[:]...)
+
+	e.timestamp = t
+}
+
+// flush writes the pending payload range, if any.
+func (e *setEncoder) flush() {
+	if e.seqlength != 0 {
+		n := varintLength64(e.seqlength)
+
+		e.buf = append(e.buf, payloadRange|byte(n))
+		e.buf = appendVarint64(e.buf, e.seqlength, n)
+
+		e.seqlength = 0
 	}
-	return CompressedSet(set)
 }
 
-func rangeLength(ids []KSUID, timestamp uint64, lastKSUID KSUID, lastValue uint96) (length int, count int) {
-	one := makeUint96(0, 1)
+// CompressIndexed creates and returns a compressed set of KSUIDs from the list
+// given as arguments, with an index allowing Contains and Seek to skip over
+// most of the set.
+func CompressIndexed(ids ...KSUID) CompressedSet {
+	c := 1 + byteLength + (len(ids) / 5)
+	b := make([]byte, 0, c)
+	return AppendCompressedIndexed(b, ids...)
+}
+
+// AppendCompressedIndexed is like AppendCompressed but the set starts with an
+// index block.
+//
+// The index costs about one raw KSUID every 64 KSUIDs of the set, it is used
+// by Contains, Len and Seek when the set was built by a single call to
+// AppendCompressedIndexed.
+func AppendCompressedIndexed(set []byte, ids ...KSUID) CompressedSet {
+	if len(ids) != 0 {
+		if !IsSorted(ids) {
+			Sort(ids)
+		}
 
-	for i := range ids {
-		id := ids[i]
+		e := setEncoder{interval: setIndexInterval}
 
-		if id == lastKSUID {
-			continue
+		for _, id := range ids {
+			e.add(id)
 		}
 
-		if id.Timestamp() != timestamp {
-			count = i
-			return
+		e.flush()
+
+		// Offsets in the index are 32 bits, larger sets are stored without
+		// an index.
+		if len(e.buf) <= math.MaxUint32 {
+			set = appendSetIndex(set, len(e.buf), e.count, e.restarts)
 		}
+		set = append(set, e.buf...)
+	}
+	return CompressedSet(set)
+}
+
+// Len returns the number of KSUIDs produced by iterating over the set.
+func (set CompressedSet) Len() int {
+	it := set.Iter()
+
+	if it.indexesAll() {
+		return int(it.index.count)
+	}
+
+	n := 0
+	for it.Next() {
+		n += 1 + int(it.skipRange(it.seqlength))
+	}
+	return n
+}
+
+// CompressedSetStats holds statistics about the encoding of a set, as
+// returned by CompressedSet.Stats.
+type CompressedSetStats struct {
+	// Entries is the number of KSUIDs in the set.
+	Entries int
+
+	// Bytes is the size of the set.
+	Bytes int
+
+	// BytesPerID is the average number of bytes used to store each KSUID,
+	// 20 bytes for a set which does not compress at all.
+	BytesPerID float64
+
+	// Tags is a histogram of the records of the set, by tag: rawKSUID,
+	// timeDelta, payloadDelta and payloadRange for the first version of the
+	// format, deltaV2 and runV2 for the second. Each name is only used by one
+	// version, so the histogram tells how much of the set each version holds.
+	Tags map[string]int
+}
 
-		v := uint96Payload(id)
+// Stats returns statistics about the encoding of the set. If the set is
+// malformed, the statistics only cover the records before the error.
+func (set CompressedSet) Stats() CompressedSetStats {
+	stats := CompressedSetStats{
+		Bytes: len(set),
+		Tags:  make(map[string]int),
+	}
 
-		if sub96(v, lastValue) != one {
-			count = i
-			return
+	for it := set.Iter(); it.Next(); {
+		stats.Entries += 1 + int(it.skipRange(it.seqlength))
+		stats.Tags[setRecordKindNames[it.kind]]++
+	}
+
+	if stats.Entries != 0 {
+		stats.BytesPerID = float64(stats.Bytes) / float64(stats.Entries)
+	}
+
+	return stats
+}
+
+// Validate checks that the set is well-formed, returning an error wrapping
+// ErrMalformedSet if it is not.
+//
+// Sets received from untrusted sources should be validated before use:
+// iterating over a malformed set stops early and reports the error through
+// the Err method of the iterator, and a malformed index could cause Contains,
+// Len and Seek to return wrong results.
+func (set CompressedSet) Validate() error {
+	it := set.Iter()
+
+	if it.indexed {
+		if err := it.validateIndex(); err != nil {
+			return err
 		}
+	}
 
-		lastKSUID = id
-		lastValue = v
-		length++
+	for it.Next() {
+		it.skipRange(it.seqlength)
 	}
+	return it.Err()
+}
+
+// Contains returns true if id is in the set.
+//
+// Sets built by CompressIndexed are searched with their index, other sets are
+// scanned linearly.
+func (set CompressedSet) Contains(id KSUID) bool {
+	it := set.Iter()
 
-	count = len(ids)
-	return
+	if it.indexesAll() {
+		return it.Seek(id) && it.KSUID == id
+	}
+
+	for it.Next() {
+		switch {
+		case it.KSUID == id:
+			return true
+		case less(&it.KSUID, &id):
+			it.seekRange(id)
+		default:
+			it.skipRange(it.seqlength)
+		}
+	}
+	return false
 }
 
 func appendVarint128(b []byte, v uint128, n int) []byte {
@@ -261,8 +615,118 @@ const (
 	timeDelta    = (1 << 6)
 	payloadDelta = (1 << 7)
 	payloadRange = (1 << 6) | (1 << 7)
+
+	// Extensions of the format use the rawKSUID tag with a non-zero count,
+	// which was never written by previous versions.
+	setIndexV1 = rawKSUID | 1
+	setBlockV2 = rawKSUID | 2
 )
 
+// setRecordKind identifies the kinds of records decoded by set iterators.
+type setRecordKind int
+
+const (
+	rawKSUIDRecord setRecordKind = iota
+	timeDeltaRecord
+	payloadDeltaRecord
+	payloadRangeRecord
+	deltaRecord
+	runRecord
+)
+
+var setRecordKindNames = [...]string{
+	rawKSUIDRecord:     "rawKSUID",
+	timeDeltaRecord:    "timeDelta",
+	payloadDeltaRecord: "payloadDelta",
+	payloadRangeRecord: "payloadRange",
+	deltaRecord:        "deltaV2",
+	runRecord:          "runV2",
+}
+
+// An index block is made of:
+//
+//	byte:     setIndexV1 tag
+//	uvarint:  length of the data following the block
+//	uvarint:  number of KSUIDs in the data
+//	uvarint:  number of restart points
+//	uint32 BE offsets of the restart points, relative to the end of the block
+//
+// A restart point is a rawKSUID record, the encoder writes one after every
+// setIndexInterval KSUIDs so iterators can start decoding from there.
+const setIndexInterval = 64
+
+// setIndex is the decoded header of an index block.
+type setIndex struct {
+	data     int
+	length   int
+	count    uint64
+	restarts []byte
+}
+
+func appendSetIndex(b []byte, length int, count uint64, restarts []uint32) []byte {
+	var c [binary.MaxVarintLen64]byte
+	b = append(b, setIndexV1)
+	b = append(b, c[:binary.PutUvarint(c[:], uint64(length))]...)
+	b = append(b, c[:binary.PutUvarint(c[:], count)]...)
+	b = append(b, c[:binary.PutUvarint(c[:], uint64(len(restarts)))]...)
+
+	for _, off := range restarts {
+		b = append(b, byte(off>>24), byte(off>>16), byte(off>>8), byte(off))
+	}
+	return b
+}
+
+// parseSetIndex decodes the index block at the beginning of b, returning
+// false if it is malformed.
+func parseSetIndex(b []byte) (idx setIndex, ok bool) {
+	if len(b) == 0 || b[0] != setIndexV1 {
+		return idx, false
+	}
+	off := 1
+
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(b[off:])
+		if n <= 0 {
+			ok = false
+			return 0
+		}
+		off += n
+		return v
+	}
+
+	ok = true
+	length := uvarint()
+	count := uvarint()
+	n := uvarint()
+
+	if !ok || n > uint64(len(b)-off)/4 {
+		return idx, false
+	}
+
+	idx.restarts = b[off : off+4*int(n)]
+	idx.data = off + 4*int(n)
+	idx.count = count
+
+	if length > uint64(len(b)-idx.data) {
+		return idx, false
+	}
+
+	idx.length = int(length)
+	return idx, true
+}
+
+// restartKSUID returns the KSUID at the i-th restart point of the index.
+func (idx *setIndex) restartKSUID(content []byte, i int) (id KSUID, off int, ok bool) {
+	off = idx.data + int(binary.BigEndian.Uint32(idx.restarts[4*i:]))
+
+	if off >= idx.data+idx.length-byteLength || content[off] != rawKSUID {
+		return id, off, false
+	}
+
+	copy(id[:], content[off+1:])
+	return id, off, true
+}
+
 // CompressedSetIter is an iterator type returned by Set.Iter to produce the
 // list of KSUIDs stored in a set.
 //
@@ -273,6 +737,10 @@ const (
 //		// ...
 //	}
 //
+//	if err := it.Err(); err != nil {
+//		// the set is malformed
+//	}
+//
 // CompressedSetIter values are not safe to use concurrently from multiple
 // goroutines.
 type CompressedSetIter struct {
@@ -280,8 +748,15 @@ type CompressedSetIter struct {
 	// by the iterator.
 	KSUID KSUID
 
-	content []byte
-	offset  int
+	content  []byte
+	offset   int
+	record   int
+	kind     setRecordKind
+	blockEnd int
+	err      error
+
+	index   setIndex
+	indexed bool
 
 	seqlength uint64
 	timestamp uint64
@@ -289,7 +764,8 @@ type CompressedSetIter struct {
 }
 
 // Next moves the iterator forward, returning true if there a KSUID was found,
-// or false if the iterator as reached the end of the set it was created from.
+// or false if the iterator as reached the end of the set it was created from,
+// or found malformed data.
 func (it *CompressedSetIter) Next() bool {
 	if it.seqlength != 0 {
 		value := incr96(it.lastValue)
@@ -299,10 +775,28 @@ func (it *CompressedSetIter) Next() bool {
 		return true
 	}
 
+	if it.blockEnd != 0 {
+		if it.offset != it.blockEnd {
+			return it.nextDelta()
+		}
+		it.blockEnd = 0
+	}
+
+	// Index blocks carry no KSUIDs, they are skipped when iterating.
+	for it.offset != len(it.content) && it.content[it.offset] == setIndexV1 {
+		it.record = it.offset
+		idx, ok := parseSetIndex(it.content[it.offset:])
+		if !ok {
+			return it.fail()
+		}
+		it.offset += idx.data
+	}
+
 	if it.offset == len(it.content) {
 		return false
 	}
 
+	it.record = it.offset
 	b := it.content[it.offset]
 	it.offset++
 
@@ -310,55 +804,390 @@ func (it *CompressedSetIter) Next() bool {
 	tag := int(b) & mask
 	cnt := int(b) & ^mask
 
-	switch tag {
-	case rawKSUID:
+	switch {
+	case tag == rawKSUID && cnt == setBlockV2:
+		length, n := binary.Uvarint(it.content[it.offset:])
+
+		if n <= 0 || length == 0 || length > uint64(len(it.content)-it.offset-n) {
+			return it.fail()
+		}
+
+		// The deltas of a block start from zero, they don't depend on the
+		// KSUIDs before the block.
+		it.offset += n
+		it.blockEnd = it.offset + int(length)
+		it.timestamp = 0
+		it.lastValue = uint96{}
+		return it.nextDelta()
+
+	case tag == rawKSUID && cnt == 0:
 		off0 := it.offset
 		off1 := off0 + byteLength
 
+		if off1 > len(it.content) {
+			return it.fail()
+		}
+
 		copy(it.KSUID[:], it.content[off0:off1])
 
 		it.offset = off1
+		it.kind = rawKSUIDRecord
 		it.timestamp = it.KSUID.Timestamp()
 		it.lastValue = uint96Payload(it.KSUID)
 
-	case timeDelta:
+	case tag == timeDelta:
 		off0 := it.offset
 		off1 := off0 + cnt
 		off2 := off1 + payloadLengthInBytes
 
+		if cnt == 0 || cnt > 8 || off2 > len(it.content) {
+			return it.fail()
+		}
+
 		it.timestamp += varint64(it.content[off0:off1])
 
 		binary.BigEndian.PutUint64(it.KSUID[:8], it.timestamp)
 		copy(it.KSUID[timestampLengthInBytes:], it.content[off1:off2])
 
 		it.offset = off2
+		it.kind = timeDeltaRecord
 		it.lastValue = uint96Payload(it.KSUID)
 
-	case payloadDelta:
+	case tag == payloadDelta:
 		off0 := it.offset
 		off1 := off0 + cnt
 
+		if cnt == 0 || cnt > payloadLengthInBytes || off1 > len(it.content) {
+			return it.fail()
+		}
+
 		delta := varint96(it.content[off0:off1])
 		value := add96(it.lastValue, delta)
 
 		it.KSUID = value.ksuid(it.timestamp)
 		it.offset = off1
+		it.kind = payloadDeltaRecord
 		it.lastValue = value
 
-	case payloadRange:
+	case tag == payloadRange:
 		off0 := it.offset
 		off1 := off0 + cnt
 
+		if cnt == 0 || cnt > 8 || off1 > len(it.content) {
+			return it.fail()
+		}
+
+		seqlength := varint64(it.content[off0:off1])
+		if seqlength == 0 {
+			return it.fail()
+		}
+
 		value := incr96(it.lastValue)
 		it.KSUID = value.ksuid(it.timestamp)
-		it.seqlength = varint64(it.content[off0:off1])
+		it.seqlength = seqlength - 1
 		it.offset = off1
-		it.seqlength--
+		it.kind = payloadRangeRecord
 		it.lastValue = value
 
 	default:
-		panic("KSUID set iterator is reading malformed data")
+		return it.fail()
 	}
 
 	return true
 }
+
+// Err returns the error which stopped the iterator, or nil if it reached the
+// end of the set or was not stopped yet. The error wraps ErrMalformedSet.
+func (it *CompressedSetIter) Err() error {
+	return it.err
+}
+
+// fail stops the iterator on malformed data at the current record.
+func (it *CompressedSetIter) fail() bool {
+	it.err = malformedSetError(it.record)
+	it.offset = len(it.content)
+	it.blockEnd = 0
+	it.seqlength = 0
+	return false
+}
+
+func malformedSetError(offset int) error {
+	return fmt.Errorf("ksuid: malformed set at offset %d: %w", offset, ErrMalformedSet)
+}
+
+// Seek moves the iterator forward to the first KSUID greater than or equal to
+// id, returning false if the end of the set was reached first.
+//
+// Seek is equivalent to calling Next until the KSUID loaded by the iterator is
+// not less than id, but uses the index of sets built by CompressIndexed to
+// skip to the closest restart point.
+func (it *CompressedSetIter) Seek(id KSUID) bool {
+	if it.indexed {
+		// Find the last restart point which is not greater than id, all
+		// KSUIDs before it are lower than id.
+		i, j := 0, len(it.index.restarts)/4
+		for i < j {
+			h := int(uint(i+j) >> 1)
+			if r, _, ok := it.index.restartKSUID(it.content, h); ok && !less(&id, &r) {
+				i = h + 1
+			} else {
+				j = h
+			}
+		}
+
+		if i != 0 {
+			if _, off, ok := it.index.restartKSUID(it.content, i-1); ok && off > it.offset {
+				it.offset = off
+				it.seqlength = 0
+			}
+		}
+	}
+
+	for it.Next() {
+		if !less(&it.KSUID, &id) {
+			return true
+		}
+
+		it.seekRange(id)
+	}
+	return false
+}
+
+// seekRange skips the KSUIDs of the current payload range which are lower
+// than id, which must be greater than the KSUID loaded by the iterator.
+//
+// Payload ranges only hold KSUIDs with the timestamp of the iterator, so they
+// can be skipped all at once.
+func (it *CompressedSetIter) seekRange(id KSUID) {
+	if it.seqlength != 0 {
+		if t := id.Timestamp(); t != it.timestamp {
+			it.skipRange(it.seqlength)
+		} else if d := sub96(uint96Payload(id), it.lastValue); d[2] != 0 {
+			it.skipRange(it.seqlength)
+		} else {
+			it.skipRange((uint64(d[1])<<32 | uint64(d[0])) - 1)
+		}
+	}
+}
+
+// validateIndex checks that the index block at the beginning of the set
+// matches the data it covers, which must be sorted so Seek can use it.
+func (it CompressedSetIter) validateIndex() error {
+	idx := it.index
+	end := idx.data + idx.length
+	restarts := idx.restarts
+	record := -1
+	count := uint64(0)
+	prev := KSUID{}
+
+	for (it.offset < end || it.seqlength != 0) && it.Next() {
+		if count != 0 && !less(&prev, &it.KSUID) {
+			return malformedSetError(it.record)
+		}
+
+		if it.record != record {
+			record = it.record
+
+			if len(restarts) != 0 {
+				switch off := idx.data + int(binary.BigEndian.Uint32(restarts)); {
+				case off == record:
+					if it.content[record] != rawKSUID {
+						return malformedSetError(record)
+					}
+					restarts = restarts[4:]
+				case off < record:
+					return malformedSetError(0)
+				}
+			}
+		}
+
+		count += 1 + it.skipRange(it.seqlength)
+		prev = it.KSUID
+	}
+
+	if it.err != nil {
+		return it.err
+	}
+
+	if it.offset != end || count != idx.count || len(restarts) != 0 {
+		return malformedSetError(0)
+	}
+
+	return nil
+}
+
+// skipRange moves the iterator forward by up to n KSUIDs of the current
+// payload range, without producing them, and returns how many were skipped.
+func (it *CompressedSetIter) skipRange(n uint64) uint64 {
+	if n > it.seqlength {
+		n = it.seqlength
+	}
+
+	if n != 0 {
+		it.lastValue = add96(it.lastValue, makeUint96(0, n))
+		it.KSUID = it.lastValue.ksuid(it.timestamp)
+		it.seqlength -= n
+	}
+
+	return n
+}
+
+// indexesAll returns true if the set starts with an index block covering all
+// the data of the set.
+func (it *CompressedSetIter) indexesAll() bool {
+	return it.indexed && it.index.data+it.index.length == len(it.content)
+}
+
+// ReverseIter returns an iterator that produces all KSUIDs in the set, in the
+// reverse order of Iter.
+func (set CompressedSet) ReverseIter() CompressedSetReverseIter {
+	return CompressedSetReverseIter{
+		it: set.Iter(),
+	}
+}
+
+// The number of records between two checkpoints of a reverse iterator.
+const reverseIterBlockSize = 64
+
+// CompressedSetReverseIter is an iterator type returned by Set.ReverseIter to
+// produce the list of KSUIDs stored in a set in descending order.
+//
+// Because the records of a set are encoded relative to the ones before them,
+// the first call to Next scans the set once and saves the state of the
+// decoder every 64 records. The set is then decoded backward one block of
+// records at a time, so the iterator never holds more than a fraction of the
+// set in memory.
+//
+// CompressedSetReverseIter values are not safe to use concurrently from
+// multiple goroutines.
+type CompressedSetReverseIter struct {
+	// KSUID is modified by calls to the Next method to hold the KSUID loaded
+	// by the iterator.
+	KSUID KSUID
+
+	it          CompressedSetIter
+	scanned     bool
+	checkpoints []setCheckpoint
+	end         int
+	records     []setRecord
+
+	timestamp uint64
+	value     uint96
+	remaining uint64
+}
+
+// setCheckpoint is the state of a set iterator at a record boundary.
+type setCheckpoint struct {
+	offset    int
+	blockEnd  int
+	timestamp uint64
+	lastValue uint96
+}
+
+// setRecord is a decoded record, it holds n KSUIDs with the same timestamp
+// and consecutive payloads, up to last.
+type setRecord struct {
+	timestamp uint64
+	last      uint96
+	n         uint64
+}
+
+// Next moves the iterator backward, returning true if there a KSUID was
+// found, or false if the iterator as reached the beginning of the set it was
+// created from, or found malformed data.
+func (it *CompressedSetReverseIter) Next() bool {
+	if !it.scanned {
+		it.scan()
+	}
+
+	for it.remaining == 0 {
+		if len(it.records) == 0 && !it.decodeBlock() {
+			return false
+		}
+
+		r := it.records[len(it.records)-1]
+		it.records = it.records[:len(it.records)-1]
+
+		it.timestamp = r.timestamp
+		it.value = r.last
+		it.remaining = r.n
+	}
+
+	it.KSUID = it.value.ksuid(it.timestamp)
+	it.value = sub96(it.value, makeUint96(0, 1))
+	it.remaining--
+	return true
+}
+
+// Err returns the error which stopped the iterator, or nil if it reached the
+// beginning of the set or was not stopped yet. The error wraps
+// ErrMalformedSet.
+//
+// The whole set is checked on the first call to Next, so no KSUIDs are
+// produced from a malformed set.
+func (it *CompressedSetReverseIter) Err() error {
+	return it.it.err
+}
+
+// scan walks the set forward to record the checkpoints of the blocks.
+func (it *CompressedSetReverseIter) scan() {
+	it.scanned = true
+
+	for n := 0; ; n++ {
+		if n%reverseIterBlockSize == 0 {
+			it.checkpoints = append(it.checkpoints, it.it.checkpoint())
+		}
+		if !it.it.Next() {
+			break
+		}
+		it.it.skipRange(it.it.seqlength)
+	}
+
+	if it.it.err != nil {
+		it.checkpoints = nil
+	}
+
+	it.end = it.it.offset
+}
+
+// decodeBlock decodes the records of the last block which was not produced
+// yet, returning false if there are none left.
+func (it *CompressedSetReverseIter) decodeBlock() bool {
+	if len(it.checkpoints) == 0 {
+		return false
+	}
+
+	c := it.checkpoints[len(it.checkpoints)-1]
+	it.checkpoints = it.checkpoints[:len(it.checkpoints)-1]
+
+	it.it.restore(c)
+
+	for it.it.offset < it.end && it.it.Next() {
+		n := 1 + it.it.skipRange(it.it.seqlength)
+		it.records = append(it.records, setRecord{
+			timestamp: it.it.timestamp,
+			last:      it.it.lastValue,
+			n:         n,
+		})
+	}
+
+	it.end = c.offset
+	return len(it.records) != 0 || it.decodeBlock()
+}
+
+func (it *CompressedSetIter) checkpoint() setCheckpoint {
+	return setCheckpoint{
+		offset:    it.offset,
+		blockEnd:  it.blockEnd,
+		timestamp: it.timestamp,
+		lastValue: it.lastValue,
+	}
+}
+
+func (it *CompressedSetIter) restore(c setCheckpoint) {
+	it.offset = c.offset
+	it.blockEnd = c.blockEnd
+	it.timestamp = c.timestamp
+	it.lastValue = c.lastValue
+	it.seqlength = 0
+}
@@ -1,6 +1,9 @@
 package ksuid
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 )
@@ -50,6 +53,10 @@ func TestCompressedSet(t *testing.T) {
 			scenario: "iterating over a compressed sequence returns the full sequence",
 			function: testCompressedSetSequence,
 		},
+		{
+			scenario: "encoding a set incrementally produces the same bytes as AppendCompressed",
+			function: testCompressedSetEncoder,
+		},
 	}
 
 	for _, test := range tests {
@@ -288,6 +295,127 @@ func testCompressedSetConcat(t *testing.T) {
 	}
 }
 
+func testCompressedSetEncoder(t *testing.T) {
+	ids := mixedKSUIDs(1000)
+	set := Compress(ids...)
+
+	e := setEncoder{}
+	for _, id := range ids {
+		e.add(id)
+		e.add(id)
+	}
+	e.flush()
+
+	if !bytes.Equal(set, e.buf) {
+		t.Error("bad encoding of the set")
+	}
+}
+
+func TestCompressedSetAlgebra(t *testing.T) {
+	all := mixedKSUIDs(2000)
+	a := make([]KSUID, 0, len(all))
+	b := make([]KSUID, 0, len(all))
+
+	// Distribute the ids so that both sets share some ranges and payload
+	// deltas, and differ on others.
+	for i, id := range all {
+		switch i % 3 {
+		case 0:
+			a = append(a, id)
+		case 1:
+			b = append(b, id)
+		default:
+			a = append(a, id)
+			b = append(b, id)
+		}
+	}
+	a = append(a, all[1000:1100]...)
+
+	inA := make(map[KSUID]bool)
+	inB := make(map[KSUID]bool)
+	for _, id := range a {
+		inA[id] = true
+	}
+	for _, id := range b {
+		inB[id] = true
+	}
+
+	setA := Compress(a...)
+	setB := Compress(b...)
+
+	tests := []struct {
+		scenario string
+		function func(a, b CompressedSet) CompressedSet
+		contains func(id KSUID) bool
+	}{
+		{
+			scenario: "Union",
+			function: Union,
+			contains: func(id KSUID) bool { return inA[id] || inB[id] },
+		},
+		{
+			scenario: "Intersect",
+			function: Intersect,
+			contains: func(id KSUID) bool { return inA[id] && inB[id] },
+		},
+		{
+			scenario: "Difference",
+			function: Difference,
+			contains: func(id KSUID) bool { return inA[id] && !inB[id] },
+		},
+		{
+			scenario: "SymmetricDifference",
+			function: SymmetricDifference,
+			contains: func(id KSUID) bool { return inA[id] != inB[id] },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			var expect []KSUID
+			for _, id := range all {
+				if test.contains(id) {
+					expect = append(expect, id)
+				}
+			}
+
+			set := test.function(setA, setB)
+
+			if !bytes.Equal(set, Compress(expect...)) {
+				t.Error("bad encoding of the result set")
+			}
+
+			if empty := test.function(nil, nil); len(empty) != 0 {
+				t.Error("bad result on empty sets:", empty)
+			}
+		})
+	}
+}
+
+// mixedKSUIDs returns a sorted list of n KSUIDs mixing random ids, sequences
+// and ids sharing their timestamp.
+func mixedKSUIDs(n int) []KSUID {
+	now := time.Now()
+	ids := make([]KSUID, 0, n)
+	seq := Sequence{Seed: New()}
+
+	for i := 0; len(ids) < n; i++ {
+		switch i % 4 {
+		case 0:
+			id, _ := seq.Next()
+			ids = append(ids, id)
+		case 1:
+			id, _ := NewRandomWithTime(now.Add(time.Duration(i%7) * time.Second))
+			ids = append(ids, id)
+		default:
+			ids = append(ids, New())
+		}
+	}
+
+	Sort(ids)
+	return Dedup(ids)
+}
+
 func reportCompressionRatio(t *testing.T, ksuids []KSUID, set CompressedSet) {
 	len1 := byteLength * len(ksuids)
 	len2 := len(set)
@@ -330,3 +458,373 @@ func BenchmarkCompressedSet(b *testing.B) {
 		b.SetBytes(int64((n * byteLength) + len(set)))
 	})
 }
+
+func TestCompressedSetSeek(t *testing.T) {
+	ids := mixedKSUIDs(1000)
+	sets := map[string]CompressedSet{
+		"plain":   Compress(ids...),
+		"indexed": CompressIndexed(ids...),
+	}
+
+	for name, set := range sets {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				target := New()
+				if i%2 == 0 {
+					target = ids[i*7]
+				}
+
+				it := set.Iter()
+				found := it.Seek(target)
+				index, _ := Search(ids, target)
+
+				switch {
+				case index == len(ids):
+					if found {
+						t.Errorf("bad seek to %s: found %s past the end of the set", target, it.KSUID)
+					}
+				case !found:
+					t.Errorf("bad seek to %s: not found", target)
+				case it.KSUID != ids[index]:
+					t.Errorf("bad seek to %s: expected %s but found %s", target, ids[index], it.KSUID)
+				default:
+					// Iterating after a seek must produce the rest of the set.
+					for index++; it.Next(); index++ {
+						if index == len(ids) || it.KSUID != ids[index] {
+							t.Fatalf("bad KSUID after seeking to %s at index %d: %s", target, index, it.KSUID)
+						}
+					}
+					if index != len(ids) {
+						t.Errorf("bad number of KSUIDs after seeking to %s: %d", target, index)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompressedSetContains(t *testing.T) {
+	ids := mixedKSUIDs(1000)
+	sets := map[string]CompressedSet{
+		"plain":   Compress(ids[:500]...),
+		"indexed": CompressIndexed(ids[:500]...),
+		"concat":  AppendCompressedIndexed(CompressIndexed(ids[250:500]...), ids[:250]...),
+	}
+
+	for name, set := range sets {
+		t.Run(name, func(t *testing.T) {
+			for i, id := range ids {
+				if contains := set.Contains(id); contains != (i < 500) {
+					t.Errorf("bad membership of %s at index %d: %t", id, i, contains)
+				}
+			}
+
+			if n := set.Len(); n != 500 {
+				t.Error("bad length:", n)
+			}
+		})
+	}
+}
+
+func TestCompressedSetIndexed(t *testing.T) {
+	ids := mixedKSUIDs(1000)
+	set := CompressIndexed(ids...)
+
+	i := 0
+	for it := set.Iter(); it.Next(); i++ {
+		if i >= len(ids) || ids[i] != it.KSUID {
+			t.Fatalf("bad KSUID at index %d: %s", i, it.KSUID)
+		}
+	}
+
+	if i != len(ids) {
+		t.Error("bad number of KSUIDs:", i)
+	}
+
+	reportCompressionRatio(t, ids, set)
+	reportCompressionRatio(t, ids, Compress(ids...))
+}
+
+func BenchmarkCompressedSetContains(b *testing.B) {
+	ids := make([]KSUID, 100000)
+	for i := range ids {
+		ids[i] = New()
+	}
+
+	plain := Compress(ids...)
+	indexed := CompressIndexed(ids...)
+
+	b.Run("plain", func(b *testing.B) {
+		for i := 0; i != b.N; i++ {
+			plain.Contains(ids[i%len(ids)])
+		}
+	})
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i != b.N; i++ {
+			indexed.Contains(ids[i%len(ids)])
+		}
+	})
+}
+
+func TestCompressedSetValidate(t *testing.T) {
+	ids := mixedKSUIDs(300)
+	id := ids[0]
+
+	valid := map[string]CompressedSet{
+		"nil":     nil,
+		"plain":   Compress(ids...),
+		"indexed": CompressIndexed(ids...),
+		"concat":  AppendCompressed(CompressIndexed(ids[100:]...), ids[:100]...),
+	}
+
+	for name, set := range valid {
+		if err := set.Validate(); err != nil {
+			t.Errorf("bad validation of the %s set: %v", name, err)
+		}
+	}
+
+	data := Compress(ids...)
+	badCount := append(appendSetIndex(nil, len(data), 1, nil), data...)
+
+	malformed := map[string]CompressedSet{
+		"unknown tag":           {0x3F},
+		"truncated raw KSUID":   append([]byte{rawKSUID}, id[:10]...),
+		"truncated time delta":  append(Compress(id), timeDelta|1, 1, 2, 3),
+		"long time delta":       append(append(Compress(id), timeDelta|9), make([]byte, 21)...),
+		"long payload delta":    append(append(Compress(id), payloadDelta|13), make([]byte, 13)...),
+		"empty payload range":   append(Compress(id), payloadRange|1, 0),
+		"truncated index":       {setIndexV1, 0x80},
+		"index count mismatch":  badCount,
+		"index out of range":    append(appendSetIndex(nil, 42, 1, nil), Compress(id)...),
+		"index restart missing": append(appendSetIndex(nil, 1+byteLength, 1, []uint32{4}), Compress(id)...),
+		"unsorted index":        append(appendSetIndex(nil, 2*(1+byteLength), 2, []uint32{0}), append(Compress(ids[1]), Compress(ids[0])...)...),
+	}
+
+	for name, set := range malformed {
+		if err := set.Validate(); !errors.Is(err, ErrMalformedSet) {
+			t.Errorf("bad validation of the set with %s: %v", name, err)
+		}
+	}
+
+	for _, set := range valid {
+		for i := range set {
+			checkCompressedSet(t, set[:i])
+		}
+	}
+}
+
+// checkCompressedSet verifies that iterating over set does not panic and
+// reports the same errors as Validate.
+func checkCompressedSet(t *testing.T, set CompressedSet) {
+	err := set.Validate()
+	if err != nil && !errors.Is(err, ErrMalformedSet) {
+		t.Fatal("bad error returned by Validate:", err)
+	}
+
+	it := set.Iter()
+	for it.Next() {
+		it.skipRange(it.seqlength)
+	}
+	if err == nil && it.Err() != nil {
+		t.Fatal("bad error returned by the iterator of a valid set:", it.Err())
+	}
+
+	set.Len()
+	set.Contains(New())
+
+	it = set.Iter()
+	it.Seek(Max)
+}
+
+func FuzzCompressedSet(f *testing.F) {
+	ids := mixedKSUIDs(200)
+	f.Add([]byte(Compress(ids...)))
+	f.Add([]byte(CompressIndexed(ids...)))
+	f.Add([]byte(Compress(ids[0])))
+	f.Add([]byte(CompressV2(ids...)))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		checkCompressedSet(t, CompressedSet(b))
+	})
+}
+
+func TestCompressedSetReverseIter(t *testing.T) {
+	ids := mixedKSUIDs(1000)
+
+	seq := Sequence{Seed: New()}
+	packed := make([]KSUID, 1000)
+	for i := range packed {
+		packed[i], _ = seq.Next()
+	}
+
+	tests := []struct {
+		scenario string
+		set      CompressedSet
+	}{
+		{scenario: "nil", set: nil},
+		{scenario: "single", set: Compress(ids[0])},
+		{scenario: "mixed", set: Compress(ids...)},
+		{scenario: "packed", set: Compress(packed...)},
+		{scenario: "indexed", set: CompressIndexed(ids...)},
+		{scenario: "concat", set: AppendCompressedIndexed(Compress(ids[500:]...), ids[:500]...)},
+		{scenario: "blocks", set: Compress(ids[:2*reverseIterBlockSize]...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			var expect []KSUID
+			for it := test.set.Iter(); it.Next(); {
+				expect = append(expect, it.KSUID)
+			}
+
+			it := test.set.ReverseIter()
+			i := len(expect)
+
+			for it.Next() {
+				i--
+				if i < 0 {
+					t.Fatal("too many KSUIDs were produced by the reverse iterator")
+				}
+				if it.KSUID != expect[i] {
+					t.Fatalf("bad KSUID at index %d: expected %s but found %s", i, expect[i], it.KSUID)
+				}
+			}
+
+			if i != 0 {
+				t.Errorf("bad number of KSUIDs: %d missing", i)
+			}
+			if err := it.Err(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		set := append(Compress(ids...), 0x3F)
+		it := set.ReverseIter()
+
+		if it.Next() {
+			t.Error("bad KSUID produced by the reverse iterator of a malformed set:", it.KSUID)
+		}
+		if err := it.Err(); !errors.Is(err, ErrMalformedSet) {
+			t.Error("bad error:", err)
+		}
+	})
+}
+
+func TestCompressedSetMarshalJSON(t *testing.T) {
+	ids := mixedKSUIDs(100)
+	set := Compress(ids...)
+
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var strs []string
+	if err := json.Unmarshal(b, &strs); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range strs {
+		if s != ids[i].String() {
+			t.Fatalf("bad KSUID at index %d: %s", i, s)
+		}
+	}
+
+	compact, err := json.Marshal(CompactCompressedSet(set))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compact) >= len(b) {
+		t.Errorf("bad compact form, larger than the array form: %d >= %d", len(compact), len(b))
+	}
+
+	for _, data := range [][]byte{b, compact} {
+		var set1 CompressedSet
+		var set2 CompactCompressedSet
+
+		if err := json.Unmarshal(data, &set1); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(set1, set) {
+			t.Errorf("bad set decoded from %.20s...", data)
+		}
+
+		if err := json.Unmarshal(data, &set2); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(set2, set) {
+			t.Errorf("bad compact set decoded from %.20s...", data)
+		}
+	}
+
+	var empty CompressedSet
+	if b, _ := json.Marshal(empty); string(b) != "[]" {
+		t.Error("bad JSON of an empty set:", string(b))
+	}
+	if err := json.Unmarshal([]byte("null"), &empty); err != nil || empty != nil {
+		t.Error("bad set decoded from null:", empty, err)
+	}
+
+	if _, err := json.Marshal(CompressedSet{0x3F}); !errors.Is(err, ErrMalformedSet) {
+		t.Error("bad error when encoding a malformed set:", err)
+	}
+	if err := json.Unmarshal([]byte(`"Pw=="`), &empty); !errors.Is(err, ErrMalformedSet) {
+		t.Error("bad error when decoding a malformed set:", err)
+	}
+}
+
+func TestCompressedSetMarshalBinary(t *testing.T) {
+	set := Compress(mixedKSUIDs(100)...)
+
+	b, err := set.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var set2 CompressedSet
+	if err := set2.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(set, set2) {
+		t.Error("bad set decoded from its binary form")
+	}
+
+	b[0] = 0x3F
+	if set[0] == b[0] || set2[0] == b[0] {
+		t.Error("bad binary form sharing memory with the set")
+	}
+	if err := set2.UnmarshalBinary(b); !errors.Is(err, ErrMalformedSet) {
+		t.Error("bad error when decoding a malformed set:", err)
+	}
+}
+
+func TestCompressedSetSql(t *testing.T) {
+	set := Compress(mixedKSUIDs(100)...)
+
+	v, err := set.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []interface{}{v, string(v.([]byte))} {
+		var set2 CompressedSet
+		if err := set2.Scan(src); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(set, set2) {
+			t.Errorf("bad set scanned from %T", src)
+		}
+	}
+
+	if v, err := CompressedSet(nil).Value(); v != nil || err != nil {
+		t.Error("bad value of an empty set:", v, err)
+	}
+
+	var set2 CompressedSet
+	if err := set2.Scan(nil); err != nil || set2 != nil {
+		t.Error("bad set scanned from nil:", set2, err)
+	}
+	if err := set2.Scan(42); err == nil {
+		t.Error("bad scan from an int")
+	}
+}
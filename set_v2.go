@@ -0,0 +1,184 @@
+package ksuid
+
+import (
+	"encoding/binary"
+)
+
+// The second version of the compressed set format stores KSUIDs in blocks
+// where each KSUID is encoded as the difference between its full 160 bits
+// value and the value of the KSUID before it, instead of splitting timestamp
+// and payload deltas. A block is made of:
+//
+//	byte:     setBlockV2 tag
+//	uvarint:  length of the records of the block
+//	records:  LEB128 varints, where the lowest bit of the first byte is a flag
+//	          and the remaining bits hold the value
+//
+// A record with the flag unset holds the delta to the next KSUID, the first
+// delta of a block is relative to Nil. A record with the flag set holds the
+// length n of a run of KSUIDs with the same timestamp and consecutive
+// payloads, like the payloadRange records of the first version.
+//
+// Because there is no tag byte, small deltas take fewer bytes than in the
+// first version. Deltas between random payloads take a few more bytes however,
+// since 1 bit out of 8 is used by LEB128 to mark the end of the varint.
+const (
+	setDeltaFlag      = 1
+	setDeltaFirstBits = 6
+	maxUvarint160Len  = 23 // (160 - 6) / 7 + 1
+)
+
+// CompressV2 creates and returns a compressed set of KSUIDs from the list
+// given as arguments, using the second version of the format.
+func CompressV2(ids ...KSUID) CompressedSet {
+	c := 3 + (len(ids) * 16)
+	b := make([]byte, 0, c)
+	return AppendCompressedV2(b, ids...)
+}
+
+// AppendCompressedV2 is like AppendCompressed but uses the second version of
+// the format, which delta-encodes the full 160 bits value of KSUIDs.
+//
+// Sets in both versions can be read and concatenated with each other. The
+// second version halves the size of sets holding subsets of sequential KSUIDs,
+// like the ones produced by a Generator or a Sequence, but uses 1 or 2 more
+// bytes per KSUID when payloads are random. Random payloads are the limiting
+// factor of both versions: no encoding can store them in less than 12 bytes,
+// whatever the precision of timestamps. CompressedSet.Stats can be used to
+// compare the two versions on actual data.
+func AppendCompressedV2(set []byte, ids ...KSUID) CompressedSet {
+	if len(ids) != 0 {
+		if !IsSorted(ids) {
+			Sort(ids)
+		}
+
+		e := setEncoderV2{}
+
+		for _, id := range ids {
+			e.add(id)
+		}
+
+		e.flush()
+
+		var c [binary.MaxVarintLen64]byte
+		set = append(set, setBlockV2)
+		set = append(set, c[:binary.PutUvarint(c[:], uint64(len(e.buf)))]...)
+		set = append(set, e.buf...)
+	}
+	return CompressedSet(set)
+}
+
+// setEncoderV2 encodes the records of a block of the second version of the
+// format. The KSUIDs must be given in ascending order, duplicates are skipped.
+type setEncoderV2 struct {
+	buf       []byte
+	last      KSUID
+	started   bool
+	seqlength uint64
+}
+
+func (e *setEncoderV2) add(id KSUID) {
+	if e.started && id == e.last {
+		return
+	}
+
+	if e.started && id.Timestamp() == e.last.Timestamp() && incr96(uint96Payload(e.last)) == uint96Payload(id) {
+		e.seqlength++
+	} else {
+		e.flush()
+		e.buf = appendUvarint160(e.buf, sub160(makeUint160(id), makeUint160(e.last)), 0)
+	}
+
+	e.last = id
+	e.started = true
+}
+
+func (e *setEncoderV2) flush() {
+	if e.seqlength != 0 {
+		e.buf = appendUvarint160(e.buf, uint160{e.seqlength}, setDeltaFlag)
+		e.seqlength = 0
+	}
+}
+
+func appendUvarint160(b []byte, v uint160, flag byte) []byte {
+	c := byte(v[0]&(1<<setDeltaFirstBits-1))<<1 | flag
+	v = shr160(v, setDeltaFirstBits)
+
+	for !v.isZero() {
+		b = append(b, c|0x80)
+		c = byte(v[0] & 0x7F)
+		v = shr160(v, 7)
+	}
+
+	return append(b, c)
+}
+
+// uvarint160 decodes a varint written by appendUvarint160, returning the
+// number of bytes read, or zero if b is too short or the varint is longer than
+// maxUvarint160Len bytes, which would overflow 160 bits.
+func uvarint160(b []byte) (v uint160, flag byte, n int) {
+	shift := uint(0)
+
+	for i, c := range b {
+		if i == maxUvarint160Len {
+			return v, 0, 0
+		}
+
+		var x uint64
+		if i == 0 {
+			flag = c & setDeltaFlag
+			x = uint64(c&0x7F) >> 1
+		} else {
+			x = uint64(c & 0x7F)
+		}
+
+		w, s := shift/64, shift%64
+		v[w] |= x << s
+		if s > 64-7 && w < 2 {
+			v[w+1] |= x >> (64 - s)
+		}
+
+		if c < 0x80 {
+			return v, flag, i + 1
+		}
+
+		if i == 0 {
+			shift += setDeltaFirstBits
+		} else {
+			shift += 7
+		}
+	}
+
+	return v, 0, 0
+}
+
+// nextDelta decodes the next record of a block of the second version of the
+// format.
+func (it *CompressedSetIter) nextDelta() bool {
+	it.record = it.offset
+
+	v, flag, n := uvarint160(it.content[it.offset:it.blockEnd])
+	if n == 0 {
+		return it.fail()
+	}
+
+	if flag == setDeltaFlag {
+		if v[1] != 0 || v[2] != 0 || v[0] == 0 {
+			return it.fail()
+		}
+
+		value := incr96(it.lastValue)
+		it.KSUID = value.ksuid(it.timestamp)
+		it.seqlength = v[0] - 1
+		it.lastValue = value
+		it.kind = runRecord
+	} else {
+		it.KSUID = add160(makeUint160(it.lastValue.ksuid(it.timestamp)), v).ksuid()
+		it.timestamp = it.KSUID.Timestamp()
+		it.lastValue = uint96Payload(it.KSUID)
+		it.kind = deltaRecord
+	}
+
+	it.offset += n
+	return true
+}
@@ -0,0 +1,245 @@
+package ksuid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestUvarint160(t *testing.T) {
+	values := []uint160{
+		{},
+		{1},
+		{63},
+		{64},
+		{1 << 63},
+		{0, 1},
+		{0, 0, 1},
+		{^uint64(0), ^uint64(0), 0xFFFFFFFF},
+	}
+
+	for _, v := range values {
+		for _, flag := range []byte{0, setDeltaFlag} {
+			b := appendUvarint160(nil, v, flag)
+
+			if len(b) > maxUvarint160Len {
+				t.Errorf("bad length of the varint of %x: %d", v, len(b))
+			}
+
+			u, f, n := uvarint160(b)
+			if u != v || f != flag || n != len(b) {
+				t.Errorf("bad varint decoded from %x: %x %d %d", b, u, f, n)
+			}
+
+			if _, _, n := uvarint160(b[:len(b)-1]); n != 0 {
+				t.Errorf("bad varint decoded from the truncated %x", b)
+			}
+		}
+	}
+
+	overflow := append(bytes.Repeat([]byte{0xFF}, maxUvarint160Len), 0x01)
+	if _, _, n := uvarint160(overflow); n != 0 {
+		t.Error("bad varint decoded from a value larger than 160 bits")
+	}
+}
+
+func TestCompressedSetV2(t *testing.T) {
+	for name, ids := range setBenchmarkData(1000) {
+		t.Run(name, func(t *testing.T) {
+			set := CompressV2(ids...)
+
+			i := 0
+			for it := set.Iter(); it.Next(); i++ {
+				if i >= len(ids) || ids[i] != it.KSUID {
+					t.Fatalf("bad KSUID at index %d: %s", i, it.KSUID)
+				}
+			}
+			if i != len(ids) {
+				t.Error("bad number of KSUIDs:", i)
+			}
+
+			if err := set.Validate(); err != nil {
+				t.Error(err)
+			}
+
+			i = len(ids)
+			for it := set.ReverseIter(); it.Next(); {
+				i--
+				if i < 0 || ids[i] != it.KSUID {
+					t.Fatalf("bad KSUID at index %d in reverse order: %s", i, it.KSUID)
+				}
+			}
+
+			for _, id := range ids[:100] {
+				if !set.Contains(id) {
+					t.Fatal("KSUID not found in the set:", id)
+				}
+			}
+
+			reportCompressionRatio(t, ids, Compress(ids...))
+			reportCompressionRatio(t, ids, set)
+		})
+	}
+}
+
+func TestCompressedSetV2Concat(t *testing.T) {
+	ids := mixedKSUIDs(300)
+
+	set := CompressedSet(nil)
+	set = AppendCompressedV2(set, ids[:100]...)
+	set = AppendCompressed(set, ids[100:200]...)
+	set = AppendCompressedV2(set, ids[200:]...)
+
+	i := 0
+	for it := set.Iter(); it.Next(); i++ {
+		if ids[i] != it.KSUID {
+			t.Fatalf("bad KSUID at index %d: %s", i, it.KSUID)
+		}
+	}
+
+	if !bytes.Equal(Union(set, nil), Compress(ids...)) {
+		t.Error("bad union of a set mixing both versions")
+	}
+}
+
+func TestCompressedSetStats(t *testing.T) {
+	seq := Sequence{Seed: New()}
+	ids := make([]KSUID, 10)
+	for i := range ids {
+		ids[i], _ = seq.Next()
+	}
+
+	set := AppendCompressedV2(Compress(ids...), ids[0], ids[2], ids[3])
+	stats := set.Stats()
+
+	if stats.Entries != 13 {
+		t.Error("bad number of entries:", stats.Entries)
+	}
+	if stats.Bytes != len(set) {
+		t.Error("bad number of bytes:", stats.Bytes)
+	}
+	if stats.BytesPerID != float64(len(set))/13 {
+		t.Error("bad number of bytes per KSUID:", stats.BytesPerID)
+	}
+
+	tags := map[string]int{"rawKSUID": 1, "payloadRange": 1, "deltaV2": 2, "runV2": 1}
+	if fmt.Sprint(stats.Tags) != fmt.Sprint(tags) {
+		t.Error("bad tags:", stats.Tags)
+	}
+}
+
+func TestCompressedSetV2Malformed(t *testing.T) {
+	id := New()
+
+	malformed := map[string]CompressedSet{
+		"empty block":       {setBlockV2, 0},
+		"truncated block":   {setBlockV2, 10, 0},
+		"truncated varint":  {setBlockV2, 1, 0x80},
+		"empty run":         {setBlockV2, 1, setDeltaFlag},
+		"large run":         append([]byte{setBlockV2, 11}, appendUvarint160(nil, uint160{0, 1}, setDeltaFlag)...),
+		"overflowing delta": append([]byte{setBlockV2, maxUvarint160Len + 1, 0xFE}, append(bytes.Repeat([]byte{0xFF}, maxUvarint160Len-1), 0x01)...),
+		"block after data":  append(CompressV2(id), 0x3F),
+	}
+
+	for name, set := range malformed {
+		if err := set.Validate(); !errors.Is(err, ErrMalformedSet) {
+			t.Errorf("bad validation of the set with %s: %v", name, err)
+		}
+		checkCompressedSet(t, set)
+	}
+}
+
+// setBenchmarkData returns lists of n sorted KSUIDs with different
+// distributions.
+func setBenchmarkData(n int) map[string][]KSUID {
+	now := time.Now()
+	data := map[string][]KSUID{}
+
+	// KSUIDs generated in a loop, as fast as possible.
+	random := make([]KSUID, n)
+	for i := range random {
+		random[i] = New()
+	}
+	data["random"] = random
+
+	// KSUIDs generated one second apart.
+	sparse := make([]KSUID, n)
+	for i := range sparse {
+		sparse[i], _ = NewRandomWithTime(now.Add(time.Duration(i) * time.Second))
+	}
+	data["sparse"] = sparse
+
+	// KSUIDs from 10 sequences.
+	packed := make([]KSUID, n)
+	seqs := [10]Sequence{}
+	for i := range seqs {
+		seqs[i] = Sequence{Seed: New()}
+	}
+	for i := range packed {
+		packed[i], _ = seqs[i%len(seqs)].Next()
+	}
+	data["packed"] = packed
+
+	// KSUIDs from a generator, which increments the payload when the clock
+	// did not move, with a clock ticking every 100 KSUIDs.
+	clock := NewFakeClock(now)
+	gen := Generator{Clock: clock}
+	generated := make([]KSUID, n)
+	for i := range generated {
+		if i%100 == 0 {
+			clock.Add(time.Microsecond)
+		}
+		generated[i], _ = gen.Next()
+	}
+	data["generator"] = generated
+
+	// Every other KSUID from a generator, as found in subsets of the KSUIDs
+	// of a sequence.
+	gaps := make([]KSUID, n)
+	for i := range gaps {
+		gaps[i], _ = gen.Next()
+		gen.Next()
+	}
+	data["gaps"] = gaps
+
+	mixed := mixedKSUIDs(n)
+	data["mixed"] = mixed
+
+	for _, ids := range data {
+		Sort(ids)
+	}
+	return data
+}
+
+func BenchmarkCompressedSetVersions(b *testing.B) {
+	versions := []struct {
+		name     string
+		compress func(...KSUID) CompressedSet
+	}{
+		{name: "v1", compress: Compress},
+		{name: "v2", compress: CompressV2},
+	}
+
+	for name, ids := range setBenchmarkData(10000) {
+		for _, v := range versions {
+			set := v.compress(ids...)
+
+			b.Run(name+"/"+v.name+"/write", func(b *testing.B) {
+				for i := 0; i != b.N; i++ {
+					v.compress(ids...)
+				}
+				b.ReportMetric(set.Stats().BytesPerID, "B/id")
+			})
+
+			b.Run(name+"/"+v.name+"/read", func(b *testing.B) {
+				for i := 0; i != b.N; i++ {
+					for it := set.Iter(); it.Next(); {
+					}
+				}
+				b.ReportMetric(set.Stats().BytesPerID, "B/id")
+			})
+		}
+	}
+}
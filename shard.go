@@ -0,0 +1,103 @@
+package ksuid
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"time"
+)
+
+// Shard returns the shard of the KSUID among n shards, a number between 0 and
+// n-1. It panics if n is lower than 1.
+//
+// The shard is computed from the payload of the KSUID only, so all KSUIDs are
+// evenly distributed whatever their time, including KSUIDs with consecutive
+// payloads like the ones produced by a Sequence. The mapping of KSUIDs to
+// shards is part of the API and will not change in future versions of the
+// package, so services routing KSUIDs with different versions agree on their
+// shards.
+//
+// Changing n moves most KSUIDs to a different shard, JumpShard should be used
+// instead when shards are added over time.
+func (i KSUID) Shard(n int) int {
+	if n < 1 {
+		panic("ksuid: the number of shards must be at least 1")
+	}
+	// Multiplying by n and keeping the high 64 bits maps the key to [0,n)
+	// without the cost of a modulo. Like a modulo, it favors some shards by at
+	// most n/2^64, which is negligible.
+	hi, _ := bits.Mul64(shardKey(i), uint64(n))
+	return int(hi)
+}
+
+// JumpShard is like Shard but uses a jump consistent hash: when the number of
+// shards grows from n to n+1, only 1/(n+1) of the KSUIDs move, and they all
+// move to the new shard. It panics if n is lower than 1.
+//
+// Shards can only be added or removed at the end of the range, JumpShard is
+// suited to partitions of Kafka topics or database shards numbered from 0,
+// not to sets of named servers. Computing the shard takes O(log n) time.
+//
+// See https://arxiv.org/abs/1406.2294 for a description of the algorithm.
+func (i KSUID) JumpShard(n int) int {
+	if n < 1 {
+		panic("ksuid: the number of shards must be at least 1")
+	}
+
+	key := shardKey(i)
+	b, j := int64(-1), int64(0)
+
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
+
+// TimeBucket returns the start of the time bucket of duration d holding the
+// time of the KSUID, in UTC, to partition KSUIDs by time. Buckets are aligned
+// on the Unix epoch, so buckets of one day start at midnight UTC and all
+// durations which divide each other produce nested buckets.
+//
+// KSUIDs with timestamps above the timestamp of MaxTime, like Max, are in the
+// bucket of MaxTime. If d is lower than or equal to zero, the time of the
+// KSUID is returned.
+func (i KSUID) TimeBucket(d time.Duration) time.Time {
+	ts := i.Timestamp()
+	if ts > maxTimestamp {
+		ts = maxTimestamp
+	}
+
+	ns := int64(ts) + epochStamp
+
+	if d > 0 {
+		r := ns % int64(d)
+		if r < 0 {
+			r += int64(d)
+		}
+		ns -= r
+	}
+
+	return time.Unix(0, ns).UTC()
+}
+
+// shardKey returns a 64 bits key computed from the whole payload of id, with
+// all bits of the payload spread to all bits of the key.
+func shardKey(id KSUID) uint64 {
+	payload := id.Payload()
+	hi := uint64(binary.BigEndian.Uint32(payload[:4]))
+	lo := binary.BigEndian.Uint64(payload[4:])
+	return mix64(lo ^ mix64(hi))
+}
+
+// mix64 is the finalizer of splitmix64, which spreads the changes of any bit
+// of x to all bits of the result.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,144 @@
+package ksuid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShard(t *testing.T) {
+	id, _ := Parse("0ujtsYcgvSTl8PAuAdqWYSMnLOv")
+
+	// The mapping of KSUIDs to shards must not change across versions.
+	if s := id.Shard(10); s != 0 {
+		t.Error("bad shard:", s)
+	}
+	if s := id.JumpShard(10); s != 4 {
+		t.Error("bad jump shard:", s)
+	}
+
+	for _, shard := range []func(KSUID, int) int{KSUID.Shard, KSUID.JumpShard} {
+		if s := shard(Max, 1); s != 0 {
+			t.Error("bad shard with one shard:", s)
+		}
+
+		// A sequence produces at most 65536 KSUIDs.
+		seq := Sequence{Seed: New()}
+
+		for _, next := range []func() KSUID{New, func() KSUID { id, _ := seq.Next(); return id }} {
+			const n = 16
+			const ids = 50000
+			counts := [n]int{}
+
+			for i := 0; i != ids; i++ {
+				s := shard(next(), n)
+				if s < 0 || s >= n {
+					t.Fatal("bad shard out of range:", s)
+				}
+				counts[s]++
+			}
+
+			// The chi-squared statistic for 15 degrees of freedom exceeds 37.7
+			// with a probability of 0.001.
+			chi2 := 0.0
+			for _, c := range counts {
+				d := float64(c) - ids/n
+				chi2 += d * d / (ids / n)
+			}
+			if chi2 > 37.7 {
+				t.Errorf("bad distribution of KSUIDs across shards: %v (chi2=%g)", counts, chi2)
+			}
+		}
+	}
+}
+
+func TestJumpShardMoves(t *testing.T) {
+	const n = 10
+	moved := 0
+
+	for i := 0; i != 10000; i++ {
+		id := New()
+		s1, s2 := id.JumpShard(n), id.JumpShard(n+1)
+
+		if s1 != s2 {
+			if s2 != n {
+				t.Fatalf("bad move of %s from shard %d to shard %d", id, s1, s2)
+			}
+			moved++
+		}
+	}
+
+	// About 1/11 of the KSUIDs are expected to move to the new shard.
+	if moved < 700 || moved > 1100 {
+		t.Error("bad number of KSUIDs moved to the new shard:", moved)
+	}
+}
+
+func TestShardPanics(t *testing.T) {
+	for _, shard := range []func(KSUID, int) int{KSUID.Shard, KSUID.JumpShard} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("bad shard computed with no shards")
+				}
+			}()
+			shard(New(), 0)
+		}()
+	}
+}
+
+func TestTimeBucket(t *testing.T) {
+	at := time.Date(2024, 3, 14, 15, 9, 26, 535897932, time.UTC)
+	id, _ := NewRandomWithTime(at)
+
+	tests := []struct {
+		d      time.Duration
+		bucket time.Time
+	}{
+		{d: 0, bucket: at},
+		{d: -time.Hour, bucket: at},
+		{d: time.Second, bucket: time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)},
+		{d: 5 * time.Minute, bucket: time.Date(2024, 3, 14, 15, 5, 0, 0, time.UTC)},
+		{d: 24 * time.Hour, bucket: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{d: 7 * 24 * time.Hour, bucket: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.d.String(), func(t *testing.T) {
+			if b := id.TimeBucket(test.d); !b.Equal(test.bucket) {
+				t.Errorf("bad time bucket: %s != %s", b, test.bucket)
+			}
+		})
+	}
+
+	if b := MaxAt(at).TimeBucket(time.Hour); !b.Equal(MinAt(at).TimeBucket(time.Hour)) {
+		t.Error("bad time bucket of the upper bound of a timestamp:", b)
+	}
+
+	if b := id.TimeBucket(time.Hour); b.Location() != time.UTC {
+		t.Error("bad location of the time bucket:", b.Location())
+	}
+
+	bucket := MaxTime.Truncate(time.Hour)
+	if b := Max.TimeBucket(time.Hour); !b.Equal(bucket) {
+		t.Errorf("bad time bucket of Max: %s != %s", b, bucket)
+	}
+	if b := Max.TimeBucket(0); !b.Equal(MaxTime) {
+		t.Errorf("bad time of Max: %s != %s", b, MaxTime)
+	}
+}
+
+func BenchmarkShard(b *testing.B) {
+	id := New()
+
+	b.Run("Shard", func(b *testing.B) {
+		for i := 0; i != b.N; i++ {
+			id.Shard(1024)
+		}
+	})
+
+	b.Run("JumpShard", func(b *testing.B) {
+		for i := 0; i != b.N; i++ {
+			id.JumpShard(1024)
+		}
+	})
+}
@@ -0,0 +1,378 @@
+package ksuid
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Slices shorter than this are sorted with an insertion sort.
+const insertionSortThreshold = 12
+
+func less(a, b *KSUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// introSort sorts a with a quicksort using a median of three pivot, falling
+// back to a heapsort when the recursion exceeds depth so the worst case stays
+// in O(n log n). Recursion only happens on the smaller partition, which bounds
+// the stack depth to O(log n).
+func introSort(a []KSUID, depth int) {
+	for len(a) > insertionSortThreshold {
+		if depth == 0 {
+			heapSort(a)
+			return
+		}
+		depth--
+
+		p := partition(a)
+
+		if p < len(a)-p {
+			introSort(a[:p], depth)
+			a = a[p+1:]
+		} else {
+			introSort(a[p+1:], depth)
+			a = a[:p]
+		}
+	}
+	insertionSort(a)
+}
+
+// partition reorders a around a pivot chosen as the median of its first,
+// middle and last elements, and returns the index of the pivot.
+func partition(a []KSUID) int {
+	hi := len(a) - 1
+	mid := hi / 2
+
+	if less(&a[mid], &a[0]) {
+		a[0], a[mid] = a[mid], a[0]
+	}
+	if less(&a[hi], &a[0]) {
+		a[0], a[hi] = a[hi], a[0]
+	}
+	if less(&a[hi], &a[mid]) {
+		a[mid], a[hi] = a[hi], a[mid]
+	}
+
+	a[mid], a[hi] = a[hi], a[mid]
+	pivot := a[hi]
+	i := 0
+
+	for j := 0; j != hi; j++ {
+		if less(&a[j], &pivot) {
+			a[i], a[j] = a[j], a[i]
+			i++
+		}
+	}
+
+	a[i], a[hi] = a[hi], a[i]
+	return i
+}
+
+func insertionSort(a []KSUID) {
+	for i := 1; i < len(a); i++ {
+		for j := i; j > 0 && less(&a[j], &a[j-1]); j-- {
+			a[j], a[j-1] = a[j-1], a[j]
+		}
+	}
+}
+
+func heapSort(a []KSUID) {
+	for i := len(a)/2 - 1; i >= 0; i-- {
+		siftDown(a, i, len(a))
+	}
+	for i := len(a) - 1; i > 0; i-- {
+		a[0], a[i] = a[i], a[0]
+		siftDown(a, 0, i)
+	}
+}
+
+func siftDown(a []KSUID, root, n int) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && less(&a[child], &a[child+1]) {
+			child++
+		}
+		if !less(&a[root], &a[child]) {
+			return
+		}
+		a[root], a[child] = a[child], a[root]
+		root = child
+	}
+}
+
+// Search searches for id in the sorted slice ids, it returns the index of the
+// first KSUID greater than or equal to id, and whether that KSUID is equal to
+// id. The returned index is len(ids) if all KSUIDs are lower than id.
+func Search(ids []KSUID, id KSUID) (int, bool) {
+	i, j := 0, len(ids)
+
+	for i < j {
+		h := int(uint(i+j) >> 1)
+
+		if less(&ids[h], &id) {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+
+	return i, i < len(ids) && ids[i] == id
+}
+
+// SearchTime returns the index of the first KSUID of the sorted slice ids
+// which was generated at or after t, or len(ids) if there are none.
+func SearchTime(ids []KSUID, t time.Time) int {
+	i, _ := Search(ids, MinAt(t))
+	return i
+}
+
+// MergeSorted merges the sorted slices a and b into a newly allocated sorted
+// slice. KSUIDs present in both slices appear twice in the result, use Dedup
+// to remove them.
+func MergeSorted(a, b []KSUID) []KSUID {
+	ids := make([]KSUID, 0, len(a)+len(b))
+
+	for len(a) != 0 && len(b) != 0 {
+		if less(&b[0], &a[0]) {
+			ids = append(ids, b[0])
+			b = b[1:]
+		} else {
+			ids = append(ids, a[0])
+			a = a[1:]
+		}
+	}
+
+	ids = append(ids, a...)
+	ids = append(ids, b...)
+	return ids
+}
+
+// Dedup removes consecutive duplicates from ids, which makes all KSUIDs of a
+// sorted slice unique. The slice is modified in place and its new length is
+// returned in the result.
+func Dedup(ids []KSUID) []KSUID {
+	if len(ids) == 0 {
+		return ids
+	}
+
+	n := 1
+
+	for _, id := range ids[1:] {
+		if id != ids[n-1] {
+			ids[n] = id
+			n++
+		}
+	}
+
+	return ids[:n]
+}
+
+const (
+	// Slices longer than this are sorted with a radix sort by Sort.
+	radixSortThreshold = 1024
+
+	// Slices longer than this are sorted by RadixSort with 16 bits digits,
+	// which halves the number of passes at the cost of larger histograms that
+	// only pay off on large inputs.
+	radixSort16Threshold = 1 << 19
+)
+
+// RadixSort sorts ids with a least significant digit radix sort, which runs
+// in O(n) passes over the 20 bytes of the KSUIDs. Passes over digits which are
+// the same for all KSUIDs, like the high bytes of timestamps, are skipped.
+//
+// The scratch slice is used as temporary storage, it is reallocated if its
+// capacity is lower than the length of ids. Programs sorting many slices can
+// reuse it to avoid allocating memory on each call.
+func RadixSort(ids []KSUID, scratch []KSUID) {
+	if len(ids) < 2 {
+		return
+	}
+
+	scratch = radixScratch(ids, scratch)
+
+	if len(ids) > radixSort16Threshold {
+		radixSort16(ids, scratch)
+	} else {
+		radixSort8(ids, scratch)
+	}
+}
+
+func radixSort8(ids []KSUID, scratch []KSUID) {
+	// The histograms of all digits are computed in a single pass since they
+	// don't depend on the order of the KSUIDs.
+	counts := new([byteLength][256]int)
+
+	for i := range ids {
+		id := &ids[i]
+		for b := range id {
+			counts[b][id[b]]++
+		}
+	}
+
+	src, dst := ids, scratch
+
+	for b := byteLength - 1; b >= 0; b-- {
+		c := &counts[b]
+
+		if c[src[0][b]] == len(src) {
+			continue
+		}
+
+		offset := 0
+		for v, n := range c {
+			c[v] = offset
+			offset += n
+		}
+
+		for i := range src {
+			v := src[i][b]
+			dst[c[v]] = src[i]
+			c[v]++
+		}
+
+		src, dst = dst, src
+	}
+
+	if &src[0] != &ids[0] {
+		copy(ids, src)
+	}
+}
+
+func radixSort16(ids []KSUID, scratch []KSUID) {
+	const digits = byteLength / 2
+
+	digit := func(id *KSUID, d int) int {
+		return int(id[2*d])<<8 | int(id[2*d+1])
+	}
+
+	counts := make([][65536]int, digits)
+
+	for i := range ids {
+		id := &ids[i]
+		for d := 0; d != digits; d++ {
+			counts[d][digit(id, d)]++
+		}
+	}
+
+	src, dst := ids, scratch
+
+	for d := digits - 1; d >= 0; d-- {
+		c := &counts[d]
+
+		if c[digit(&src[0], d)] == len(src) {
+			continue
+		}
+
+		offset := 0
+		for v, n := range c {
+			c[v] = offset
+			offset += n
+		}
+
+		for i := range src {
+			v := digit(&src[i], d)
+			dst[c[v]] = src[i]
+			c[v]++
+		}
+
+		src, dst = dst, src
+	}
+
+	if &src[0] != &ids[0] {
+		copy(ids, src)
+	}
+}
+
+// RadixSortParallel is like RadixSort but splits the work of each pass across
+// the given number of goroutines.
+func RadixSortParallel(ids []KSUID, scratch []KSUID, workers int) {
+	if workers < 2 || len(ids) < workers*radixSortThreshold {
+		RadixSort(ids, scratch)
+		return
+	}
+
+	scratch = radixScratch(ids, scratch)
+	counts := make([][256]int, workers)
+	chunk := (len(ids) + workers - 1) / workers
+	src, dst := ids, scratch
+
+	parallel := func(f func(w int, lo, hi int)) {
+		wg := sync.WaitGroup{}
+		for w := 0; w != workers; w++ {
+			lo := w * chunk
+			hi := lo + chunk
+			if hi > len(ids) {
+				hi = len(ids)
+			}
+			wg.Add(1)
+			go func(w, lo, hi int) {
+				defer wg.Done()
+				f(w, lo, hi)
+			}(w, lo, hi)
+		}
+		wg.Wait()
+	}
+
+	for b := byteLength - 1; b >= 0; b-- {
+		parallel(func(w, lo, hi int) {
+			c := &counts[w]
+			*c = [256]int{}
+			for i := lo; i < hi; i++ {
+				c[src[i][b]]++
+			}
+		})
+
+		if radixSkip(counts, src[0][b], len(src)) {
+			continue
+		}
+
+		// Each worker scatters its chunk at offsets following the ones of
+		// the previous chunks for the same byte value, which keeps the sort
+		// stable.
+		offset := 0
+		for v := 0; v != 256; v++ {
+			for w := range counts {
+				n := counts[w][v]
+				counts[w][v] = offset
+				offset += n
+			}
+		}
+
+		parallel(func(w, lo, hi int) {
+			c := &counts[w]
+			for i := lo; i < hi; i++ {
+				v := src[i][b]
+				dst[c[v]] = src[i]
+				c[v]++
+			}
+		})
+
+		src, dst = dst, src
+	}
+
+	if &src[0] != &ids[0] {
+		copy(ids, src)
+	}
+}
+
+// radixSkip returns true if all n KSUIDs counted in the histograms have the
+// byte value v, in which case the pass can be skipped.
+func radixSkip(counts [][256]int, v byte, n int) bool {
+	total := 0
+	for w := range counts {
+		total += counts[w][v]
+	}
+	return total == n
+}
+
+func radixScratch(ids, scratch []KSUID) []KSUID {
+	if cap(scratch) < len(ids) {
+		return make([]KSUID, len(ids))
+	}
+	return scratch[:len(ids)]
+}
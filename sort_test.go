@@ -0,0 +1,263 @@
+package ksuid
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// sortInputs returns slices of n KSUIDs in the orders that are known to be
+// challenging for sorting algorithms.
+func sortInputs(n int) map[string][]KSUID {
+	random := make([]KSUID, n)
+	for i := range random {
+		random[i] = New()
+	}
+
+	sorted := append([]KSUID(nil), random...)
+	sort.Slice(sorted, func(i, j int) bool { return Compare(sorted[i], sorted[j]) < 0 })
+
+	reversed := make([]KSUID, n)
+	for i, id := range sorted {
+		reversed[n-i-1] = id
+	}
+
+	duplicates := make([]KSUID, n)
+	for i := range duplicates {
+		duplicates[i] = random[i%4]
+	}
+
+	return map[string][]KSUID{
+		"random":     random,
+		"sorted":     sorted,
+		"reversed":   reversed,
+		"duplicates": duplicates,
+	}
+}
+
+func TestSortInputs(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 11, 12, 13, 100, 10000} {
+		for name, input := range sortInputs(n) {
+			t.Run(fmt.Sprintf("%s/%d", name, n), func(t *testing.T) {
+				ids := append([]KSUID(nil), input...)
+				expected := append([]KSUID(nil), input...)
+				sort.Slice(expected, func(i, j int) bool { return Compare(expected[i], expected[j]) < 0 })
+
+				Sort(ids)
+
+				for i := range ids {
+					if ids[i] != expected[i] {
+						t.Fatalf("bad KSUID at index %d: %s != %s", i, ids[i], expected[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRadixSort(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 100, 1000, 100000, radixSort16Threshold + 1} {
+		for name, input := range sortInputs(n) {
+			t.Run(fmt.Sprintf("%s/%d", name, n), func(t *testing.T) {
+				expected := append([]KSUID(nil), input...)
+				introSort(expected, 64)
+
+				for _, workers := range []int{1, 3, 8} {
+					ids := append([]KSUID(nil), input...)
+
+					if workers == 1 {
+						RadixSort(ids, nil)
+					} else {
+						RadixSortParallel(ids, make([]KSUID, 0, n/2), workers)
+					}
+
+					for i := range ids {
+						if ids[i] != expected[i] {
+							t.Fatalf("bad KSUID at index %d with %d workers: %s != %s", i, workers, ids[i], expected[i])
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRadixSortSameTimestamp(t *testing.T) {
+	ids, _ := NewBatch(10000, time.Now())
+	ids = append(ids, sortInputs(10000)["random"]...)
+
+	// Mix KSUIDs sharing the timestamp of the batch with random KSUIDs, so
+	// the passes over the digits of the payload see unordered input.
+	rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+
+	RadixSort(ids, make([]KSUID, len(ids)))
+
+	if !IsSorted(ids) {
+		t.Error("not sorted")
+	}
+}
+
+func TestHeapSort(t *testing.T) {
+	ids := sortInputs(1000)["random"]
+	heapSort(ids)
+
+	if !IsSorted(ids) {
+		t.Error("not sorted")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ids := sortInputs(100)["sorted"]
+
+	for i, id := range ids {
+		if j, found := Search(ids, id); j != i || !found {
+			t.Errorf("Search(%s) = %d, %t, expected %d, true", id, j, found, i)
+		}
+
+		if j, found := Search(ids, id.Next()); j != i+1 || found {
+			t.Errorf("Search(%s) = %d, %t, expected %d, false", id.Next(), j, found, i+1)
+		}
+	}
+
+	if i, found := Search(ids, Nil); i != 0 || found {
+		t.Errorf("Search(Nil) = %d, %t", i, found)
+	}
+
+	if i, found := Search(nil, Max); i != 0 || found {
+		t.Errorf("Search(nil, Max) = %d, %t", i, found)
+	}
+}
+
+func TestSearchTime(t *testing.T) {
+	now := time.Now()
+	ids := make([]KSUID, 10)
+
+	for i := range ids {
+		ids[i], _ = NewRandomWithTime(now.Add(time.Duration(i) * time.Second))
+	}
+
+	for i := range ids {
+		if j := SearchTime(ids, now.Add(time.Duration(i)*time.Second)); j != i {
+			t.Errorf("bad index for time +%ds: %d", i, j)
+		}
+	}
+
+	if j := SearchTime(ids, now.Add(-time.Second)); j != 0 {
+		t.Error("bad index for a time before all KSUIDs:", j)
+	}
+
+	if j := SearchTime(ids, now.Add(time.Hour)); j != len(ids) {
+		t.Error("bad index for a time after all KSUIDs:", j)
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	ids := sortInputs(100)["sorted"]
+
+	var a, b []KSUID
+	for i, id := range ids {
+		if i%3 == 0 {
+			a = append(a, id)
+		} else {
+			b = append(b, id)
+		}
+	}
+
+	merged := MergeSorted(a, b)
+
+	if len(merged) != len(ids) {
+		t.Fatal("bad length:", len(merged))
+	}
+
+	for i := range ids {
+		if merged[i] != ids[i] {
+			t.Fatalf("bad KSUID at index %d: %s != %s", i, merged[i], ids[i])
+		}
+	}
+
+	if merged := MergeSorted(a, a); len(merged) != 2*len(a) || !IsSorted(merged) {
+		t.Error("merging a slice with itself did not keep the duplicates")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	ids := sortInputs(10)["sorted"]
+
+	var dupes []KSUID
+	for i, id := range ids {
+		for j := 0; j <= i%3; j++ {
+			dupes = append(dupes, id)
+		}
+	}
+
+	uniq := Dedup(dupes)
+
+	if len(uniq) != len(ids) {
+		t.Fatal("bad length:", len(uniq))
+	}
+
+	for i := range ids {
+		if uniq[i] != ids[i] {
+			t.Fatalf("bad KSUID at index %d: %s != %s", i, uniq[i], ids[i])
+		}
+	}
+
+	if uniq := Dedup(nil); len(uniq) != 0 {
+		t.Error("bad length for a nil slice:", len(uniq))
+	}
+}
+
+func BenchmarkSortInputs(b *testing.B) {
+	for _, name := range []string{"random", "sorted", "reversed", "duplicates"} {
+		input := sortInputs(10000)[name]
+		ids := make([]KSUID, len(input))
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i != b.N; i++ {
+				copy(ids, input)
+				Sort(ids)
+			}
+		})
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	ids := sortInputs(10000)["sorted"]
+
+	for i := 0; i != b.N; i++ {
+		Search(ids, ids[i%len(ids)])
+	}
+}
+
+func BenchmarkRadixSort(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000, 1000000} {
+		input := sortInputs(n)["random"]
+		ids := make([]KSUID, n)
+		scratch := make([]KSUID, n)
+
+		b.Run(fmt.Sprintf("introsort/%d", n), func(b *testing.B) {
+			for i := 0; i != b.N; i++ {
+				copy(ids, input)
+				introSort(ids, 64)
+			}
+		})
+
+		b.Run(fmt.Sprintf("radix/%d", n), func(b *testing.B) {
+			for i := 0; i != b.N; i++ {
+				copy(ids, input)
+				RadixSort(ids, scratch)
+			}
+		})
+
+		b.Run(fmt.Sprintf("radix-parallel/%d", n), func(b *testing.B) {
+			for i := 0; i != b.N; i++ {
+				copy(ids, input)
+				RadixSortParallel(ids, scratch, 4)
+			}
+		})
+	}
+}
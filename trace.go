@@ -0,0 +1,88 @@
+package ksuid
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+)
+
+const (
+	traceIDLength = 16
+	spanIDLength  = 8
+)
+
+// TraceID derives a W3C trace-context trace ID from the KSUID.
+//
+// The trace ID is made of the 8 bytes timestamp of the KSUID followed by the
+// first 8 bytes of its payload, so trace IDs derived from KSUIDs sort by time
+// like the KSUIDs themselves, and the time can be recovered with TraceIDTime.
+// The last 7 bytes are random, as required by the trace-context random flag.
+func (i KSUID) TraceID() (id [traceIDLength]byte) {
+	copy(id[:], i[:traceIDLength])
+	return
+}
+
+// SpanID derives a W3C trace-context span ID from the KSUID.
+//
+// The span ID is made of the last 8 bytes of the payload of the KSUID, span
+// IDs only need to be unique within a trace so they carry no timestamp.
+//
+// Bytes 12 to 15 of the KSUID are part of both its trace ID and its span ID,
+// so the span ID of a KSUID shares 32 bits with the trace ID derived from the
+// same KSUID. Spans should get their IDs from a different KSUID than the trace.
+func (i KSUID) SpanID() (id [spanIDLength]byte) {
+	copy(id[:], i[byteLength-spanIDLength:])
+	return
+}
+
+// TraceIDTime returns the time of the KSUID that the trace ID was derived
+// from by TraceID.
+func TraceIDTime(id [traceIDLength]byte) time.Time {
+	return correctedUTCTimestampToTime(binary.BigEndian.Uint64(id[:timestampLengthInBytes]))
+}
+
+// TraceIDGenerator generates time-sortable trace IDs and random span IDs from
+// KSUIDs, using the same source of randomness as New.
+//
+// Its methods match the IDGenerator interface of the OpenTelemetry SDK
+// (go.opentelemetry.io/otel/sdk/trace) except for the named ID types. Since
+// trace.TraceID and trace.SpanID are byte arrays, a small adapter is enough to
+// plug it into a tracer provider:
+//
+//	type idGenerator struct{ ksuid.TraceIDGenerator }
+//
+//	func (g idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+//		return g.TraceIDGenerator.NewIDs(ctx)
+//	}
+//
+//	func (g idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+//		return g.TraceIDGenerator.NewSpanID(ctx, traceID)
+//	}
+//
+//	provider := sdktrace.NewTracerProvider(sdktrace.WithIDGenerator(idGenerator{}))
+//
+// The zero-value is ready to use. TraceIDGenerator values are safe to use
+// concurrently from multiple goroutines.
+type TraceIDGenerator struct{}
+
+// NewIDs returns a new trace ID and span ID, derived from two new KSUIDs so
+// that the span ID shares no bits with the trace ID.
+func (TraceIDGenerator) NewIDs(ctx context.Context) (traceID [traceIDLength]byte, spanID [spanIDLength]byte) {
+	return New().TraceID(), nonZeroSpanID(New())
+}
+
+// NewSpanID returns a new span ID for the trace identified by traceID.
+func (TraceIDGenerator) NewSpanID(ctx context.Context, traceID [traceIDLength]byte) [spanIDLength]byte {
+	return nonZeroSpanID(New())
+}
+
+// nonZeroSpanID returns the span ID of id, or of another KSUID if it is zero,
+// which is an invalid span ID.
+func nonZeroSpanID(id KSUID) [spanIDLength]byte {
+	for {
+		if spanID := id.SpanID(); spanID != [spanIDLength]byte{} {
+			return spanID
+		}
+		id = New()
+	}
+}
@@ -0,0 +1,80 @@
+package ksuid
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTraceID(t *testing.T) {
+	id := New()
+	traceID := id.TraceID()
+
+	if !bytes.Equal(traceID[:], id[:16]) {
+		t.Errorf("bad trace ID: %X", traceID)
+	}
+
+	if tm := TraceIDTime(traceID); !tm.Equal(id.Time()) {
+		t.Error("bad trace ID time:", tm, "!=", id.Time())
+	}
+}
+
+func TestTraceIDOrder(t *testing.T) {
+	now := time.Now()
+	ids := make([]KSUID, 100)
+
+	for i := range ids {
+		ids[i], _ = NewRandomWithTime(now.Add(time.Duration(i) * time.Nanosecond))
+	}
+
+	for i := 1; i < len(ids); i++ {
+		t1 := ids[i-1].TraceID()
+		t2 := ids[i].TraceID()
+
+		if bytes.Compare(t1[:], t2[:]) >= 0 {
+			t.Errorf("trace IDs are not sorted by time: %X >= %X", t1, t2)
+		}
+	}
+}
+
+func TestSpanID(t *testing.T) {
+	id := New()
+	spanID := id.SpanID()
+
+	if !bytes.Equal(spanID[:], id[12:]) {
+		t.Errorf("bad span ID: %X", spanID)
+	}
+}
+
+func TestTraceIDGenerator(t *testing.T) {
+	var gen TraceIDGenerator
+	ctx := context.Background()
+
+	t0 := time.Now()
+	traceID, spanID := gen.NewIDs(ctx)
+	t1 := time.Now()
+
+	if tm := TraceIDTime(traceID); tm.Before(t0) || tm.After(t1) {
+		t.Error("bad trace ID time:", tm)
+	}
+
+	if spanID == ([8]byte{}) {
+		t.Error("zero span ID")
+	}
+
+	if bytes.Equal(traceID[12:], spanID[:4]) {
+		t.Error("span ID sharing bits with the trace ID:", traceID, spanID)
+	}
+
+	seen := map[[8]byte]struct{}{spanID: {}}
+
+	for i := 0; i != 1000; i++ {
+		spanID := gen.NewSpanID(ctx, traceID)
+
+		if _, dupe := seen[spanID]; dupe {
+			t.Fatalf("duplicate span ID: %X", spanID)
+		}
+		seen[spanID] = struct{}{}
+	}
+}
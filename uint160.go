@@ -0,0 +1,54 @@
+package ksuid
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// uint160 represents an unsigned 160 bits little endian integer, which holds
+// the value of a whole KSUID.
+type uint160 [3]uint64 // [0] holds low 64 bits, [1] holds middle 64 bits, [2] holds high 32 bits
+
+func makeUint160(id KSUID) uint160 {
+	return uint160{
+		binary.BigEndian.Uint64(id[12:]),
+		binary.BigEndian.Uint64(id[4:12]),
+		uint64(binary.BigEndian.Uint32(id[:4])),
+	}
+}
+
+func (v uint160) ksuid() (out KSUID) {
+	binary.BigEndian.PutUint32(out[:4], uint32(v[2]))
+	binary.BigEndian.PutUint64(out[4:12], v[1])
+	binary.BigEndian.PutUint64(out[12:], v[0])
+	return
+}
+
+func (v uint160) isZero() bool {
+	return v[0]|v[1]|v[2] == 0
+}
+
+func add160(x, y uint160) (z uint160) {
+	var c uint64
+	z[0], c = bits.Add64(x[0], y[0], 0)
+	z[1], c = bits.Add64(x[1], y[1], c)
+	z[2] = uint64(uint32(x[2] + y[2] + c))
+	return
+}
+
+func sub160(x, y uint160) (z uint160) {
+	var b uint64
+	z[0], b = bits.Sub64(x[0], y[0], 0)
+	z[1], b = bits.Sub64(x[1], y[1], b)
+	z[2] = uint64(uint32(x[2] - y[2] - b))
+	return
+}
+
+// shr160 shifts v right by n bits, n must be lower than 64.
+func shr160(v uint160, n uint) uint160 {
+	return uint160{
+		v[0]>>n | v[1]<<(64-n),
+		v[1]>>n | v[2]<<(64-n),
+		v[2] >> n,
+	}
+}
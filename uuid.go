@@ -0,0 +1,90 @@
+package ksuid
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrInvalidUUIDv7 is returned by FromUUIDv7 when the UUID does not have the
+// version and variant bits of a UUIDv7.
+var ErrInvalidUUIDv7 = errors.New("Valid UUIDv7 values have the version 7 and the RFC 9562 variant")
+
+const (
+	uuidVersion7     = 0x70
+	uuidVariantRFC   = 0x80
+	uuidFracBits     = 12
+	uuidPayloadShift = 2 // 64 - 62 bits of rand_b
+
+	nanosPerMilli = 1000000
+	epochMillis   = uint64(epochStamp / nanosPerMilli)
+	maxMillis     = uint64((math.MaxInt64-epochStamp)/nanosPerMilli) - 1
+)
+
+// ToUUIDv7 converts the KSUID to a UUIDv7 as specified by RFC 9562, which can
+// be stored in columns that only accept 16 bytes UUIDs.
+//
+// The fields of the UUID are populated as follows:
+//
+//	unix_ts_ms (48 bits): the time of the KSUID in milliseconds
+//	rand_a     (12 bits): the sub-millisecond fraction of the time (RFC 9562
+//	                      section 6.2, method 3)
+//	rand_b     (62 bits): the 62 most significant bits of the payload
+//
+// Because a KSUID holds 160 bits and a UUIDv7 only 122, the conversion loses
+// the precision of the time below 1/4096th of a millisecond (about 244ns) and
+// the 34 least significant bits of the payload. The UUIDs sort in the same
+// order as the KSUIDs they were converted from, but distinct KSUIDs may
+// convert to the same UUID.
+//
+// The conversion is lossless for KSUIDs returned by FromUUIDv7: converting a
+// UUIDv7 to a KSUID and back always yields the original UUID.
+func (i KSUID) ToUUIDv7() (u [16]byte) {
+	ts := i.Timestamp()
+	ms := ts/nanosPerMilli + epochMillis
+	frac := ((ts % nanosPerMilli) << uuidFracBits) / nanosPerMilli
+
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], ms)
+	copy(u[:6], b[2:])
+
+	u[6] = uuidVersion7 | byte(frac>>8)
+	u[7] = byte(frac)
+
+	binary.BigEndian.PutUint64(u[8:], binary.BigEndian.Uint64(i[timestampLengthInBytes:])>>uuidPayloadShift)
+	u[8] |= uuidVariantRFC
+	return
+}
+
+// FromUUIDv7 converts a UUIDv7 to a KSUID, it is the inverse of ToUUIDv7.
+//
+// The time of the KSUID is the time of the UUID, rounded up to the closest
+// nanosecond, and the 62 bits of rand_b become the most significant bits of
+// the payload, the remaining bits being set to zero.
+//
+// ErrInvalidUUIDv7 is returned if u does not have the version and variant
+// bits of a UUIDv7, and ErrTimeOutOfRange if its time cannot be represented
+// by a KSUID.
+func FromUUIDv7(u [16]byte) (KSUID, error) {
+	if (u[6]&0xF0) != uuidVersion7 || (u[8]&0xC0) != uuidVariantRFC {
+		return Nil, ErrInvalidUUIDv7
+	}
+
+	var b [8]byte
+	copy(b[2:], u[:6])
+	ms := binary.BigEndian.Uint64(b[:])
+
+	if ms < epochMillis || ms-epochMillis > maxMillis {
+		return Nil, ErrTimeOutOfRange
+	}
+
+	// Rounding up guarantees that the fraction computed by ToUUIDv7 is the one
+	// found in the UUID.
+	frac := uint64(u[6]&0x0F)<<8 | uint64(u[7])
+	ts := (ms-epochMillis)*nanosPerMilli + (frac*nanosPerMilli+(1<<uuidFracBits)-1)>>uuidFracBits
+
+	var ksuid KSUID
+	binary.BigEndian.PutUint64(ksuid[:timestampLengthInBytes], ts)
+	binary.BigEndian.PutUint64(ksuid[timestampLengthInBytes:], binary.BigEndian.Uint64(u[8:])<<uuidPayloadShift)
+	return ksuid, nil
+}
@@ -0,0 +1,125 @@
+package ksuid
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestToUUIDv7(t *testing.T) {
+	tm := time.Date(2024, 1, 1, 0, 0, 0, 500000, time.UTC) // 0.5ms
+	id, _ := FromParts(tm, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	u := id.ToUUIDv7()
+
+	var b [8]byte
+	copy(b[2:], u[:6])
+
+	if ms := binary.BigEndian.Uint64(b[:]); ms != uint64(tm.UnixNano()/1e6) {
+		t.Error("bad unix_ts_ms:", ms, "!=", tm.UnixNano()/1e6)
+	}
+
+	if v := u[6] >> 4; v != 7 {
+		t.Error("bad version:", v)
+	}
+
+	if frac := int(u[6]&0x0F)<<8 | int(u[7]); frac != 2048 {
+		t.Error("bad sub-millisecond fraction:", frac, "!= 2048")
+	}
+
+	if v := u[8] >> 6; v != 2 {
+		t.Error("bad variant:", v)
+	}
+
+	if !bytes.Equal(u[9:], []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}) || (u[8]&0x3F) != 0x3F {
+		t.Errorf("bad rand_b: %X", u[8:])
+	}
+}
+
+func TestUUIDv7RoundTrip(t *testing.T) {
+	now := time.Now()
+
+	for i := 0; i != 1000; i++ {
+		id, _ := NewRandomWithTime(now.Add(time.Duration(i) * 123457 * time.Nanosecond))
+		u1 := id.ToUUIDv7()
+
+		k, err := FromUUIDv7(u1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if u2 := k.ToUUIDv7(); u1 != u2 {
+			t.Fatalf("UUIDv7 does not round-trip: %X != %X", u1, u2)
+		}
+
+		if k.ToUUIDv7() != id.ToUUIDv7() {
+			t.Fatalf("KSUIDs %s and %s have different UUIDs", id, k)
+		}
+
+		if d := id.Time().Sub(k.Time()); d < -time.Microsecond || d > time.Microsecond {
+			t.Fatalf("time of the KSUID is too far from the original: %s", d)
+		}
+	}
+}
+
+func TestUUIDv7RoundTripRandom(t *testing.T) {
+	for i := 0; i != 1000; i++ {
+		var u1 [16]byte
+		rand.Read(u1[:])
+		u1[0], u1[1] = 0x01, 0x90 // a time after the KSUID epoch
+		u1[6] = 0x70 | (u1[6] & 0x0F)
+		u1[8] = 0x80 | (u1[8] & 0x3F)
+
+		k, err := FromUUIDv7(u1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if u2 := k.ToUUIDv7(); u1 != u2 {
+			t.Fatalf("UUIDv7 does not round-trip: %X != %X", u1, u2)
+		}
+	}
+}
+
+func TestUUIDv7Order(t *testing.T) {
+	ids, _ := NewBatch(100, time.Now())
+	ids = append(ids, MaxAt(time.Now().Add(time.Millisecond)))
+
+	for i := 1; i < len(ids); i++ {
+		u1 := ids[i-1].ToUUIDv7()
+		u2 := ids[i].ToUUIDv7()
+
+		if bytes.Compare(u1[:], u2[:]) > 0 {
+			t.Errorf("UUIDs are not in the order of the KSUIDs: %X > %X", u1, u2)
+		}
+	}
+}
+
+func TestFromUUIDv7Errors(t *testing.T) {
+	u := New().ToUUIDv7()
+
+	v4 := u
+	v4[6] = 0x40 | (v4[6] & 0x0F)
+	if _, err := FromUUIDv7(v4); err != ErrInvalidUUIDv7 {
+		t.Error("bad error for a UUIDv4:", err)
+	}
+
+	variant := u
+	variant[8] &= 0x3F
+	if _, err := FromUUIDv7(variant); err != ErrInvalidUUIDv7 {
+		t.Error("bad error for an invalid variant:", err)
+	}
+
+	early := u
+	copy(early[:6], []byte{0, 0, 0, 0, 0, 1})
+	if _, err := FromUUIDv7(early); err != ErrTimeOutOfRange {
+		t.Error("bad error for a time before the KSUID epoch:", err)
+	}
+
+	late := u
+	copy(late[:6], []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	if _, err := FromUUIDv7(late); err != ErrTimeOutOfRange {
+		t.Error("bad error for a time after the maximum KSUID time:", err)
+	}
+}